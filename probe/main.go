@@ -12,14 +12,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"io"
 	"net/http"
 	. "s3gw-ha/probe/utils"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/gin-gonic/gin"
 	"github.com/igrmk/treemap/v2"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -34,7 +39,11 @@ func main() {
 	flag.StringVar(&Cfg.SaveDataBucket, "save-data-bucket", "s3gw-ha-testing", "The bucket where to save results")
 	flag.UintVar(&Cfg.WaitMSecsBeforeTriggerDeath, "wbtd", 0, "Wait n milliseconds before trigger death")
 	flag.UintVar(&Cfg.WaitMSecsBeforeSetReplicas1, "wbsr", 0, "Wait n milliseconds before set replicas 1")
+	flag.UintVar(&Cfg.SettleMSecsAfterFrontendUp, "settle", 1000, "Window in milliseconds after frontend-up still attributed to a restart")
 	flag.StringVar(&Cfg.CollectRestartAtEvent, "collectAt", "frontend-up", "The event where the probe should collect a restart event")
+	flag.StringVar(&Cfg.StorePath, "store", "", "File of the run store persisting the collected events, disabled when empty")
+	flag.StringVar(&Cfg.OTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP endpoint (host:port) where to export the traces, disabled when empty")
+	flag.BoolVar(&Cfg.OTLPInsecure, "otlp-insecure", true, "Export the traces without TLS")
 	flag.StringVar(&Cfg.LogLevel, "v", "inf", "Specify logging verbosity [off, trc, inf, wrn, err]")
 	flag.UintVar(&Cfg.VerbLevel, "vl", 5, "Verbosity level")
 
@@ -44,12 +53,42 @@ func main() {
 
 	Prb.CollectedRestartRelatedData = make(map[string][]RestartEvent)
 	Prb.CollectedS3WorkloadRelatedData = make(map[string]*treemap.TreeMap[int64, S3WorkloadEvent])
+	Prb.CollectedS3WorkloadHistograms = make(S3WorkloadHistograms)
+	Prb.CollectedS3WorkloadErrors = make(S3WorkloadErrorCounts)
+	Prb.CollectedS3WorkloadIntegrity = make(S3WorkloadIntegrity)
+	Prb.ImportedMarks = make(map[string]string)
 	Prb.S3WorkloadEvtChan = make(chan string)
+	Prb.CurrentSettlePeriod = Cfg.SettleMSecsAfterFrontendUp
+	Prb.CurrentPercentiles = DefaultPercentiles
+	Prb.CurrentCI = DefaultBootstrapConfig
+	Prb.CurrentOutliers = DefaultOutlierConfig()
+	Prb.CurrentPlot = DefaultPlotConfig()
 
 	Logger = GetLogger(&Cfg)
 
 	Logger.Infof("Params:%v", Cfg)
 
+	//Tracing
+
+	if shutdownTracing, err := InitTracing(Cfg.OTLPEndpoint, Cfg.OTLPInsecure); err == nil {
+		defer shutdownTracing()
+	} else {
+		Logger.Errorf("InitTracing:%s", err.Error())
+	}
+
+	//Run store
+
+	if Cfg.StorePath != "" {
+		if store, err := OpenRunStore(Cfg.StorePath); err == nil {
+			Prb.Store = store
+			if err := Prb.LoadFromStore(); err != nil {
+				Logger.Errorf("LoadFromStore:%s", err.Error())
+			}
+		} else {
+			Logger.Errorf("OpenRunStore:%s", err.Error())
+		}
+	}
+
 	//S3Clients
 
 	S3Client_S3GW = InitS3Client_S3GW()
@@ -64,6 +103,8 @@ func main() {
 
 	K8sCli.Init()
 
+	Prb.ResumeCampaign()
+
 	//GIN
 
 	router := gin.Default()
@@ -74,6 +115,15 @@ func main() {
 	router.PUT("/trigger", trigger)
 	router.POST("/clear", clear)
 	router.POST("/fill", fill)
+	router.GET("/fill", fillProgress)
+	router.GET("/histograms", histograms)
+	router.GET("/compare", compare)
+	router.GET("/slo", slo)
+	router.GET("/history", history)
+	router.GET("/events", events)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.POST("/history/import", historyImport)
+	router.GET("/hooks", hooks)
 	router.POST("/set_replicas", set_replicas)
 	router.POST("/set_taint", set_taint)
 
@@ -121,13 +171,41 @@ func computeStats(c *gin.Context) {
 		dumpAllData = true
 	}
 
+	percentiles, err := ParsePercentiles(c.Query("percentiles"))
+	if err != nil {
+		Logger.Errorf("malformed percentiles:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ci, err := ParseBootstrapConfig(c.Query("ci_resamples"), c.Query("ci_level"))
+	if err != nil {
+		Logger.Errorf("malformed confidence interval config:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outliers, err := ParseOutlierConfig(c.Query("outliers"), c.Query("outlier_k"), c.Query("outlier_ns"))
+	if err != nil {
+		Logger.Errorf("malformed outlier config:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	plotCfg, err := ParsePlotConfig(c.Query)
+	if err != nil {
+		Logger.Errorf("malformed plot config:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
 	genTS := strconv.Itoa(int(time.Now().Unix()))
-	stats := Stats{TimeUnit: timeUnit}
+	stats := Stats{TimeUnit: timeUnit, Percentiles: percentiles, CI: ci, Outliers: outliers, Plot: plotCfg}
 
 	Prb.ComputeRestartStats(&stats, mark, timeUnit, dumpAllData)
 	Prb.ComputeS3WorkloadStats(&stats, mark, timeUnit, dumpAllData)
 
-	fNames := Prb.Render(genTS, timeUnit, stats)
+	fNames := Prb.Render(genTS, timeUnit, mark, Prb.RenderMarks(mark), stats, nil)
 	SendStatsArtifactsToS3(S3Client_SaveData, Cfg.SaveDataBucket, fNames)
 
 	c.JSON(http.StatusOK, stats)
@@ -148,6 +226,12 @@ func trigger(c *gin.Context) {
 		Prb.CurrentLieDownPeriod = 0
 	}
 
+	if val, err := strconv.ParseUint(c.Query("settle"), 0, 32); err == nil {
+		Prb.CurrentSettlePeriod = uint(val)
+	} else {
+		Prb.CurrentSettlePeriod = Cfg.SettleMSecsAfterFrontendUp
+	}
+
 	if restarts, err := strconv.ParseUint(c.Query("restarts"), 0, 32); err == nil {
 		Prb.CurrentPendingRestarts = uint(restarts)
 	} else {
@@ -155,13 +239,79 @@ func trigger(c *gin.Context) {
 		c.String(http.StatusBadRequest, err.Error())
 	}
 
+	if percentiles, err := ParsePercentiles(c.Query("percentiles")); err == nil {
+		Prb.CurrentPercentiles = percentiles
+	} else {
+		Logger.Errorf("malformed percentiles:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if ci, err := ParseBootstrapConfig(c.Query("ci_resamples"), c.Query("ci_level")); err == nil {
+		Prb.CurrentCI = ci
+	} else {
+		Logger.Errorf("malformed confidence interval config:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if outliers, err := ParseOutlierConfig(c.Query("outliers"), c.Query("outlier_k"), c.Query("outlier_ns")); err == nil {
+		Prb.CurrentOutliers = outliers
+	} else {
+		Logger.Errorf("malformed outlier config:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if plotCfg, err := ParsePlotConfig(c.Query); err == nil {
+		Prb.CurrentPlot = plotCfg
+	} else {
+		Logger.Errorf("malformed plot config:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if Prb.IsImportedMark(c.Query("mark")) {
+		c.String(http.StatusBadRequest, "read-only imported mark: "+c.Query("mark"))
+		return
+	}
+
 	Prb.CurrentDeathType = c.Query("how")
 	Prb.CurrentMark = c.Query("mark")
+	Prb.CurrentRunId = strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	if hooks, err := parseHooks(c); err == nil {
+		Prb.CurrentHooks = hooks
+	} else {
+		Logger.Errorf("malformed hook:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	Prb.CurrentSLOs = nil
+	for _, sloStr := range c.QueryArray("slo") {
+		if slo, err := ParseSLO(sloStr); err == nil {
+			Prb.CurrentSLOs = append(Prb.CurrentSLOs, *slo)
+			//the percentiles an SLO is set on must be computed
+			if perc, isPerc := slo.Percentile(); isPerc && !containsFloat(Prb.CurrentPercentiles, perc) {
+				Prb.CurrentPercentiles = append(append([]float64{}, Prb.CurrentPercentiles...), perc)
+			}
+		} else {
+			Logger.Errorf("malformed slo:%s", err.Error())
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	switch interposeFunc := c.Query("interpose"); interposeFunc {
 	case "fill":
-		Prb.CurrentGinCtx = c.Copy()
-		Prb.CurrentInterposeFunc = fill
+		//legacy interposition: fill between restarts with the trigger's query string
+		args := make(map[string]string)
+		for key := range c.Request.URL.Query() {
+			args[key] = c.Query(key)
+		}
+		args["pl"] = c.Query("payload")
+		Prb.CurrentHooks = append(Prb.CurrentHooks, Hook{Phase: HookPhasePreDeath, Action: "fill", Args: args})
 	}
 
 	node := c.Query("node")
@@ -177,12 +327,39 @@ func trigger(c *gin.Context) {
 		Prb.CurrentS3WorkloadCfg.Frequency = 1000
 	}
 
-	if c.Query("s3-wl-ing") == "1" {
-		Prb.CurrentS3WorkloadCfg.Client = S3Client_S3GW_ingress
+	Prb.CurrentS3WorkloadCfg.KeepRaw = c.Query("s3-wl-raw") != "0"
+	Prb.CurrentS3WorkloadCfg.Ingress = c.Query("s3-wl-ing") == "1"
+
+	if policy, err := ParseS3ClientPolicy(c.Query("s3-wl-conn-timeout"), c.Query("s3-wl-req-timeout"), c.Query("s3-wl-retries")); err == nil {
+		Prb.CurrentS3WorkloadCfg.Policy = policy
 	} else {
-		Prb.CurrentS3WorkloadCfg.Client = S3Client_S3GW
+		Logger.Errorf("malformed s3 workload client policy:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
 	}
 
+	Prb.CurrentS3WorkloadCfg.InitClient()
+
+	//a new sweep restarts counting its restarts from 0
+	Prb.CurrentSweep = nil
+	if steps, err := strconv.ParseUint(c.Query("sweep"), 0, 32); err == nil && steps > 0 {
+		fillCfg, _, err := ParseFillConfig(fillParGetter(c))
+		if err != nil {
+			Logger.Errorf("malformed sweep fill:%s", err.Error())
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		Prb.CurrentSweep = &SweepConfig{Steps: uint(steps),
+			RestartsPerStep: Prb.CurrentPendingRestarts,
+			BaseMark:        Prb.CurrentMark,
+			FillCfg:         fillCfg}
+		Prb.CurrentPendingRestarts *= uint(steps)
+		Prb.CurrentHooks = append(Prb.CurrentHooks, Hook{Phase: HookPhasePreDeath, Action: HookActionSweepStep})
+		Prb.SweepStep()
+	}
+
+	Prb.RecordCampaign()
+	SetProbeState(ProbeStateRunning)
 	Prb.RequestDie()
 }
 
@@ -191,21 +368,224 @@ func clear(c *gin.Context) {
 }
 
 func fill(c *gin.Context) {
-	bucket := c.Query("bucket")
-	objBaseName := c.Query("obj_base_name")
-	payload := c.Query("payload")
-	objCountPar := c.Query("obj_count")
-	erase := c.Query("erase")
-	addTSPar := c.Query("add-ts")
-	addTS := addTSPar == "1"
-	if objCount, err := strconv.ParseUint(objCountPar, 0, 64); err == nil {
-		FillWithObjects(S3Client_S3GW, bucket, objBaseName, payload, objCount, addTS)
-		if erase == "1" {
-			EraseObjects(S3Client_S3GW, bucket, objBaseName, objCount)
+	if cfg, erase, err := ParseFillConfig(fillParGetter(c)); err == nil {
+		if summaries, err := RunFill(cfg, true, erase); err == nil {
+			c.JSON(http.StatusOK, summaries)
+		} else {
+			c.String(http.StatusConflict, err.Error())
+		}
+	} else {
+		Logger.Errorf("malformed fill request:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+	}
+}
+
+func fillParGetter(c *gin.Context) func(string) string {
+	return func(key string) string {
+		if key == "pl" {
+			return c.Query("payload")
+		}
+		return c.Query(key)
+	}
+}
+
+// parseHooks parses the hook query string parameters; a query string with ';'
+// is rejected, since the pairs containing it are dropped by the query parsing.
+func parseHooks(c *gin.Context) ([]Hook, error) {
+	if strings.Contains(c.Request.URL.RawQuery, ";") {
+		return nil, errors.New("invalid ';' in query string, hook arguments are separated by ','")
+	}
+	var hooks []Hook
+	for _, hookStr := range c.QueryArray("hook") {
+		hook, err := ParseHook(hookStr)
+		if err != nil {
+			return nil, err
 		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, nil
+}
+
+func hooks(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"actions": HookActionNames(), "current": Prb.CurrentHooks})
+}
+
+func fillProgress(c *gin.Context) {
+	c.JSON(http.StatusOK, FillPrg.Summary())
+}
+
+func history(c *gin.Context) {
+	if entries, err := Prb.ListHistory(c.Query("prefix")); err == nil {
+		c.JSON(http.StatusOK, entries)
 	} else {
-		Logger.Errorf("malformed objCountPar:%s", err.Error())
+		c.String(http.StatusInternalServerError, err.Error())
+	}
+}
+
+func historyImport(c *gin.Context) {
+	keys := c.QueryArray("key")
+	if len(keys) == 0 {
+		c.String(http.StatusBadRequest, "missing key")
+		return
+	}
+
+	imported := make(map[string][]string)
+	for _, key := range keys {
+		marks, err := Prb.ImportHistory(key)
+		if err != nil {
+			Logger.Errorf("ImportHistory:%s", err.Error())
+			c.String(http.StatusBadRequest, key+": "+err.Error())
+			return
+		}
+		imported[key] = marks
+	}
+	c.JSON(http.StatusOK, imported)
+}
+
+func containsFloat(list []float64, val float64) bool {
+	for _, it := range list {
+		if it == val {
+			return true
+		}
+	}
+	return false
+}
+
+// events streams the live events as Server-Sent Events,
+// optionally only the requested types.
+func events(c *gin.Context) {
+	types := make(map[string]bool)
+	for _, evtType := range c.QueryArray("type") {
+		types[evtType] = true
+	}
+
+	ch, unsubscribe := LiveFeed.Subscribe()
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			c.SSEvent("keepalive", "")
+		case evt := <-ch:
+			if len(types) == 0 || types[evt.Type] {
+				c.SSEvent(evt.Type, evt)
+			}
+		}
+		return true
+	})
+}
+
+// slo returns the SLO verdict of the last campaign: status is pass, fail,
+// running while a campaign with SLOs is in progress, none otherwise.
+func slo(c *gin.Context) {
+	if Prb.CurrentPendingRestarts > 0 && len(Prb.CurrentSLOs) > 0 {
+		c.JSON(http.StatusOK, gin.H{"status": "running", "mark": Prb.CurrentMark, "slos": Prb.CurrentSLOs})
+		return
+	}
+	if Prb.LastSLOVerdict == nil {
+		c.JSON(http.StatusOK, gin.H{"status": SLOStatusNone})
+		return
+	}
+	c.JSON(http.StatusOK, Prb.LastSLOVerdict)
+}
+
+func compare(c *gin.Context) {
+	cfg := CompareConfig{Base: c.Query("base"),
+		Candidate:      c.Query("candidate"),
+		TimeUnit:       c.DefaultQuery("time_unit", "ms"),
+		Threshold:      DefaultCompareThreshold,
+		AvailThreshold: DefaultCompareAvailThreshold,
+		Alpha:          DefaultCompareAlpha}
+
+	if _, hit := StrTimeUnit2TimeUnit[cfg.TimeUnit]; !hit {
+		c.String(http.StatusBadRequest, "unknown time_unit: "+cfg.TimeUnit)
+		return
+	}
+
+	var err error
+	if cfg.Percentiles, err = ParsePercentiles(c.Query("percentiles")); err != nil {
+		Logger.Errorf("malformed percentiles:%s", err.Error())
 		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for par, val := range map[string]*float64{"threshold": &cfg.Threshold,
+		"avail_threshold": &cfg.AvailThreshold,
+		"alpha":           &cfg.Alpha} {
+		if c.Query(par) == "" {
+			continue
+		}
+		if *val, err = strconv.ParseFloat(c.Query(par), 64); err != nil {
+			Logger.Errorf("malformed %s:%s", par, err.Error())
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	plotCfg, err := ParsePlotConfig(c.Query)
+	if err != nil {
+		Logger.Errorf("malformed plot config:%s", err.Error())
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := Prb.CompareMarks(cfg)
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+
+	genTS := strconv.Itoa(int(time.Now().Unix()))
+	if fNames, err := Prb.GenerateCompareOverlayPlot(cfg, genTS, plotCfg); err == nil {
+		SendStatsArtifactsToS3(S3Client_SaveData, Cfg.SaveDataBucket, fNames)
+	}
+	marks := []string{cfg.Base, cfg.Candidate}
+	name := cfg.Base + "_vs_" + cfg.Candidate
+	if fNames, err := Prb.GenerateCDFPlots(marks, name, cfg.TimeUnit, genTS, plotCfg); err == nil {
+		SendStatsArtifactsToS3(S3Client_SaveData, Cfg.SaveDataBucket, fNames)
+	}
+	if fNames, err := Prb.GenerateHistogramPlots(marks, name, cfg.TimeUnit, genTS, plotCfg); err == nil {
+		SendStatsArtifactsToS3(S3Client_SaveData, Cfg.SaveDataBucket, fNames)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// histograms exports the S3 workload latency histograms in the HdrHistogram log format;
+// with merge=1 the histograms of the requested marks are merged into a single one.
+func histograms(c *gin.Context) {
+	marks := strings.Split(c.DefaultQuery("mark", Prb.CurrentMark), ",")
+	op := c.Query("op")
+
+	Prb.S3WorkloadMtx.Lock()
+	defer Prb.S3WorkloadMtx.Unlock()
+
+	var hists []*hdrhistogram.Histogram
+	if c.Query("merge") == "1" {
+		merged := Prb.CollectedS3WorkloadHistograms.Merged(marks, op)
+		merged.SetTag("merged")
+		hists = append(hists, merged)
+	} else {
+		for _, mark := range marks {
+			for _, histOp := range Prb.CollectedS3WorkloadHistograms.Ops(mark) {
+				if op == "" || op == histOp {
+					hists = append(hists, Prb.CollectedS3WorkloadHistograms[mark][histOp])
+				}
+			}
+		}
+	}
+
+	c.Header("Content-Type", "text/plain")
+	c.Status(http.StatusOK)
+	if err := WriteHistogramLog(c.Writer, hists); err != nil {
+		Logger.Errorf("WriteHistogramLog:%s", err.Error())
 	}
 }
 
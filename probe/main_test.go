@@ -0,0 +1,54 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http/httptest"
+	. "s3gw-ha/probe/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestContext(target string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("PUT", target, nil)
+	return c
+}
+
+func TestParseHooks(t *testing.T) {
+	//the example of the README
+	c := newTestContext("/trigger?restarts=10&how=exit0&mark=my-test&hook=pre-death:fill:bucket=b,obj_base_name=o,obj_count=100,every=2")
+	hooks, err := parseHooks(c)
+	if err != nil {
+		t.Fatalf("parseHooks: %s", err.Error())
+	}
+	if len(hooks) != 1 {
+		t.Fatalf("parseHooks: got %d hooks, want 1", len(hooks))
+	}
+	if hook := hooks[0]; hook.Phase != HookPhasePreDeath || hook.Action != "fill" || hook.Every != 2 ||
+		hook.Args["bucket"] != "b" || hook.Args["obj_base_name"] != "o" || hook.Args["obj_count"] != "100" {
+		t.Errorf("parseHooks: got %+v", hook)
+	}
+
+	c = newTestContext("/trigger?restarts=2&hook=post-fup:wait:ms=10&hook=pre-death:scale:replicas=1")
+	if hooks, err := parseHooks(c); err != nil || len(hooks) != 2 {
+		t.Errorf("parseHooks: got %+v, %v, want 2 hooks", hooks, err)
+	}
+
+	//the pairs with ';' would be silently dropped by the query parsing
+	c = newTestContext("/trigger?restarts=10&hook=pre-death:fill:bucket=b;obj_base_name=o;obj_count=100")
+	if _, err := parseHooks(c); err == nil {
+		t.Errorf("parseHooks: query string with ';' accepted")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+// ComputeDowntimes groups the consecutive failed S3 workload entries attributed
+// to the same restart (see CorrelateS3WorkloadEntries), a successful entry
+// closing the group, and computes, for each group, the unavailability window
+// observed by the client: from the first failing request to the first
+// successful request following the last failing one.
+func ComputeDowntimes(evtSeries []S3WorkloadEntry, timeUnit int64) []DowntimeEntry {
+	var downtimes []DowntimeEntry
+	var lastFailIdxs []int
+	inDowntime := false
+
+	for idx, it := range evtSeries {
+		if it.ErrDesc == "" {
+			//a successful request closes the current downtime
+			inDowntime = false
+			continue
+		}
+		if !inDowntime || downtimes[len(downtimes)-1].RestartId != it.RestartId {
+			inDowntime = true
+			downtimes = append(downtimes, DowntimeEntry{RestartId: it.RestartId, FirstFailure: it.Start})
+			lastFailIdxs = append(lastFailIdxs, idx)
+		}
+		lastDowntime := &downtimes[len(downtimes)-1]
+		lastDowntime.LastFailure = it.Start
+		lastDowntime.FailedCount++
+		lastFailIdxs[len(lastFailIdxs)-1] = idx
+	}
+
+	for i := range downtimes {
+		recoveryTs := evtSeries[lastFailIdxs[i]].End
+		for _, it := range evtSeries[lastFailIdxs[i]+1:] {
+			if it.ErrDesc == "" {
+				recoveryTs = it.Start
+				break
+			}
+		}
+		downtimes[i].Unavailability = float64(recoveryTs-downtimes[i].FirstFailure) / float64(timeUnit)
+	}
+
+	return downtimes
+}
+
+// ComputeAvailability fills the client observed availability metrics
+// of a S3 workload series.
+func ComputeAvailability(series *SeriesS3WorkloadEntry, evtSeries []S3WorkloadEntry, timeUnit int64) {
+	series.TotalCount = uint(len(evtSeries))
+	if len(evtSeries) == 0 {
+		return
+	}
+
+	series.Downtimes = ComputeDowntimes(evtSeries, timeUnit)
+
+	for _, it := range series.Downtimes {
+		series.Unavailability += it.Unavailability
+	}
+
+	if len(series.Downtimes) > 0 {
+		series.FirstFailure = series.Downtimes[0].FirstFailure
+		series.LastFailure = series.Downtimes[len(series.Downtimes)-1].LastFailure
+	}
+
+	series.Availability = 100 * float64(series.TotalCount-series.ErrCount) / float64(series.TotalCount)
+
+	runDuration := float64(evtSeries[len(evtSeries)-1].End-evtSeries[0].Start) / float64(timeUnit)
+	if runDuration > 0 {
+		series.TimeAvailability = 100 * (1 - series.Unavailability/runDuration)
+	} else {
+		series.TimeAvailability = series.Availability
+	}
+}
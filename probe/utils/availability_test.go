@@ -0,0 +1,56 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeDowntimes(t *testing.T) {
+	fail := func(start int64, restartId int) S3WorkloadEntry {
+		return S3WorkloadEntry{Start: start, End: start + 1, RestartId: restartId, ErrDesc: "err"}
+	}
+	ok := func(start int64, restartId int) S3WorkloadEntry {
+		return S3WorkloadEntry{Start: start, End: start + 1, RestartId: restartId}
+	}
+
+	tests := []struct {
+		name   string
+		series []S3WorkloadEntry
+		want   []DowntimeEntry
+	}{
+		{"fail/ok/fail outside restarts",
+			[]S3WorkloadEntry{fail(10, 0), ok(20, 0), fail(100, 0), ok(110, 0)},
+			[]DowntimeEntry{
+				{RestartId: 0, FirstFailure: 10, LastFailure: 10, Unavailability: 10, FailedCount: 1},
+				{RestartId: 0, FirstFailure: 100, LastFailure: 100, Unavailability: 10, FailedCount: 1}}},
+		{"consecutive failures of a restart",
+			[]S3WorkloadEntry{ok(0, 0), fail(10, 1), fail(20, 1), ok(30, 1)},
+			[]DowntimeEntry{{RestartId: 1, FirstFailure: 10, LastFailure: 20, Unavailability: 20, FailedCount: 2}}},
+		{"failures across restarts",
+			[]S3WorkloadEntry{fail(10, 1), fail(20, 2), ok(30, 2)},
+			[]DowntimeEntry{
+				{RestartId: 1, FirstFailure: 10, LastFailure: 10, Unavailability: 20, FailedCount: 1},
+				{RestartId: 2, FirstFailure: 20, LastFailure: 20, Unavailability: 10, FailedCount: 1}}},
+		{"no recovery",
+			[]S3WorkloadEntry{ok(0, 0), fail(10, 1)},
+			[]DowntimeEntry{{RestartId: 1, FirstFailure: 10, LastFailure: 10, Unavailability: 1, FailedCount: 1}}},
+		{"no failures", []S3WorkloadEntry{ok(0, 0), ok(10, 0)}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := ComputeDowntimes(tt.series, 1); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ComputeDowntimes() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+
+	"github.com/montanaflynn/stats"
+)
+
+// the resampling is seeded with a constant so that the same data
+// always yields the same intervals.
+const bootstrapSeed = 1
+
+// BootstrapConfig configures the bootstrap confidence intervals,
+// Resamples 0 disables them.
+type BootstrapConfig struct {
+	Resamples uint    `json:"resamples"`
+	Level     float64 `json:"level"` //percentage, e.g. 95
+}
+
+var DefaultBootstrapConfig = BootstrapConfig{Resamples: 1000, Level: 95}
+
+const bootstrapMaxResamples = 100000
+
+// ConfInterval is a confidence interval around an estimate.
+type ConfInterval struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// ParseBootstrapConfig parses the number of resamples and the confidence level,
+// empty values are defaulted.
+func ParseBootstrapConfig(resamples string, level string) (BootstrapConfig, error) {
+	cfg := DefaultBootstrapConfig
+	if resamples != "" {
+		val, err := strconv.ParseUint(resamples, 0, 32)
+		if err != nil {
+			return cfg, err
+		}
+		if val > bootstrapMaxResamples {
+			return cfg, errors.New("ci_resamples above " + strconv.Itoa(bootstrapMaxResamples) + ": " + resamples)
+		}
+		cfg.Resamples = uint(val)
+	}
+	if level != "" {
+		val, err := strconv.ParseFloat(level, 64)
+		if err != nil {
+			return cfg, err
+		}
+		if val <= 0 || val >= 100 {
+			return cfg, errors.New("confidence level out of range (0, 100): " + level)
+		}
+		cfg.Level = val
+	}
+	return cfg, nil
+}
+
+// BootstrapCI computes the percentile bootstrap confidence interval of
+// the statistic estimated on data.
+func BootstrapCI(data []float64, statistic func([]float64) (float64, error), cfg BootstrapConfig) (ConfInterval, error) {
+	if cfg.Resamples == 0 {
+		return ConfInterval{}, errors.New("bootstrap disabled")
+	}
+	if len(data) == 0 {
+		return ConfInterval{}, stats.EmptyInputErr
+	}
+
+	rnd := rand.New(rand.NewSource(bootstrapSeed))
+	resample := make([]float64, len(data))
+	estimates := make([]float64, 0, cfg.Resamples)
+
+	for i := uint(0); i < cfg.Resamples; i++ {
+		for j := range resample {
+			resample[j] = data[rnd.Intn(len(data))]
+		}
+		if val, err := statistic(resample); err == nil {
+			estimates = append(estimates, val)
+		}
+	}
+
+	alpha := (100 - cfg.Level) / 2
+	low, err := stats.Percentile(estimates, alpha)
+	if err != nil {
+		return ConfInterval{}, err
+	}
+	high, err := stats.Percentile(estimates, 100-alpha)
+	if err != nil {
+		return ConfInterval{}, err
+	}
+	return ConfInterval{Low: low, High: high}, nil
+}
+
+// PercentileCI computes the bootstrap confidence interval of the
+// (interpolated) percentile of data.
+func PercentileCI(data []float64, perc float64, cfg BootstrapConfig) (ConfInterval, error) {
+	return BootstrapCI(data, func(d []float64) (float64, error) {
+		return stats.Percentile(d, perc)
+	}, cfg)
+}
+
+// ComputeDistSummaryCI fills the summary with the bootstrap confidence
+// intervals of the mean and of the (interpolated) percentiles.
+func ComputeDistSummaryCI(summary *DistSummary, data []float64, percentiles []float64, cfg BootstrapConfig) {
+	if cfg.Resamples == 0 || len(data) == 0 {
+		return
+	}
+
+	if ci, err := BootstrapCI(data, func(d []float64) (float64, error) {
+		return stats.Mean(d)
+	}, cfg); err == nil {
+		summary.MeanCI = &ci
+	}
+
+	summary.PercentilesCI = make(map[string]ConfInterval)
+	for _, perc := range percentiles {
+		if ci, err := PercentileCI(data, perc, cfg); err == nil {
+			summary.PercentilesCI[PercentileKey(perc)] = ci
+		}
+	}
+}
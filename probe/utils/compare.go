@@ -0,0 +1,255 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	DefaultCompareThreshold      = 10   //% of increase of a duration
+	DefaultCompareAvailThreshold = 0.1  //percentage points of decrease of an availability
+	DefaultCompareAlpha          = 0.05 //significance level of the Mann-Whitney U test
+)
+
+// CompareConfig drives the comparison of a candidate mark against a base mark.
+type CompareConfig struct {
+	Base           string    `json:"base"`
+	Candidate      string    `json:"candidate"`
+	TimeUnit       string    `json:"time_unit"`
+	Percentiles    []float64 `json:"percentiles"`
+	Threshold      float64   `json:"threshold"`
+	AvailThreshold float64   `json:"avail_threshold"`
+	Alpha          float64   `json:"alpha"`
+}
+
+// MetricComparison compares the samples of a metric of two marks,
+// deltas are candidate - base.
+type MetricComparison struct {
+	Metric      string             `json:"metric"`
+	Base        DistSummary        `json:"base"`
+	Candidate   DistSummary        `json:"candidate"`
+	Delta       map[string]float64 `json:"delta"`
+	DeltaPct    map[string]float64 `json:"delta_pct"`
+	U           float64            `json:"U"`
+	Z           float64            `json:"z"`
+	PValue      float64            `json:"p_value"`
+	Significant bool               `json:"significant"`
+	Regression  bool               `json:"regression"`
+}
+
+// ScalarComparison compares a single value of two marks.
+type ScalarComparison struct {
+	Metric         string  `json:"metric"`
+	Base           float64 `json:"base"`
+	Candidate      float64 `json:"candidate"`
+	Delta          float64 `json:"delta"`
+	HigherIsBetter bool    `json:"higher_is_better"`
+	Regression     bool    `json:"regression"`
+}
+
+type CompareReport struct {
+	Config      CompareConfig      `json:"config"`
+	Metrics     []MetricComparison `json:"metrics"`
+	Scalars     []ScalarComparison `json:"scalars"`
+	Regression  bool               `json:"regression"`
+	Regressions []string           `json:"regressions"`
+}
+
+// MannWhitneyU runs the two-sided Mann-Whitney U test on the samples a and b,
+// using the normal approximation with tie and continuity corrections.
+// It returns the U statistic of a, the z score and the p-value.
+func MannWhitneyU(a []float64, b []float64) (float64, float64, float64, error) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, 0, 1, errors.New("empty sample")
+	}
+
+	type sample struct {
+		val   float64
+		fromA bool
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, val := range a {
+		all = append(all, sample{val, true})
+	}
+	for _, val := range b {
+		all = append(all, sample{val, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].val < all[j].val })
+
+	//average ranks for ties
+	rankSumA := 0.0
+	tieTerm := 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].val == all[i].val {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+
+	u := rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	mu := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1))))
+	if sigma == 0 {
+		return u, 0, 1, nil
+	}
+
+	diff := u - mu
+	switch {
+	case diff > 0.5:
+		diff -= 0.5
+	case diff < -0.5:
+		diff += 0.5
+	default:
+		diff = 0
+	}
+	z := diff / sigma
+	return u, z, math.Erfc(math.Abs(z) / math.Sqrt2), nil
+}
+
+// compareSamples compares the samples of a duration metric: a regression is
+// a significant increase of the median or of a percentile beyond the threshold.
+func compareSamples(metric string, base []float64, candidate []float64, cfg *CompareConfig) MetricComparison {
+	cmp := MetricComparison{Metric: metric,
+		Base:      ComputeDistSummary(base, cfg.Percentiles),
+		Candidate: ComputeDistSummary(candidate, cfg.Percentiles),
+		Delta:     make(map[string]float64),
+		DeltaPct:  make(map[string]float64)}
+
+	baseVals := map[string]float64{"mean": cmp.Base.Mean, "median": cmp.Base.Median}
+	candVals := map[string]float64{"mean": cmp.Candidate.Mean, "median": cmp.Candidate.Median}
+	for key, val := range cmp.Base.Percentiles {
+		baseVals[key] = val
+	}
+	for key, val := range cmp.Candidate.Percentiles {
+		candVals[key] = val
+	}
+
+	worse := false
+	for key, baseVal := range baseVals {
+		candVal, hit := candVals[key]
+		if !hit {
+			continue
+		}
+		cmp.Delta[key] = candVal - baseVal
+		if baseVal != 0 {
+			cmp.DeltaPct[key] = (candVal - baseVal) / baseVal * 100
+			if key != "mean" && cmp.DeltaPct[key] > cfg.Threshold {
+				worse = true
+			}
+		}
+	}
+
+	if u, z, pValue, err := MannWhitneyU(base, candidate); err == nil {
+		cmp.U, cmp.Z, cmp.PValue = u, z, pValue
+		cmp.Significant = pValue < cfg.Alpha
+	} else {
+		cmp.PValue = 1
+	}
+
+	cmp.Regression = worse && cmp.Significant
+	return cmp
+}
+
+func compareScalars(metric string, base float64, candidate float64, higherIsBetter bool, threshold float64) ScalarComparison {
+	cmp := ScalarComparison{Metric: metric,
+		Base:           base,
+		Candidate:      candidate,
+		Delta:          candidate - base,
+		HigherIsBetter: higherIsBetter}
+	if higherIsBetter {
+		cmp.Regression = -cmp.Delta > threshold
+	} else {
+		cmp.Regression = cmp.Delta > threshold
+	}
+	return cmp
+}
+
+// CompareMarks compares every restart and workload metric of the candidate mark against the base mark.
+func (p *Probe) CompareMarks(cfg CompareConfig) (*CompareReport, error) {
+	if len(cfg.Percentiles) == 0 {
+		cfg.Percentiles = DefaultPercentiles
+	}
+	tu := StrTimeUnit2TimeUnit[cfg.TimeUnit]
+
+	baseRestarts, hitBase := p.CollectedRestartRelatedData[cfg.Base]
+	candRestarts, hitCand := p.CollectedRestartRelatedData[cfg.Candidate]
+	if !hitBase || !hitCand {
+		return nil, errors.New("no restart series for base or candidate mark")
+	}
+
+	report := CompareReport{Config: cfg}
+
+	_, baseMain, baseFUp, baseDelta := GetSplitDataForSingleRestartRelatedData(baseRestarts, tu)
+	_, candMain, candFUp, candDelta := GetSplitDataForSingleRestartRelatedData(candRestarts, tu)
+
+	report.Metrics = append(report.Metrics,
+		compareSamples("to_main", baseMain, candMain, &cfg),
+		compareSamples("to_frontend_up", baseFUp, candFUp, &cfg),
+		compareSamples("frontend_up_main_delta", baseDelta, candDelta, &cfg))
+
+	_, baseRTT, hitBaseWL := p.s3WorkloadEntries(cfg.Base, baseRestarts, tu)
+	_, candRTT, hitCandWL := p.s3WorkloadEntries(cfg.Candidate, candRestarts, tu)
+	if hitBaseWL && hitCandWL {
+
+		report.Metrics = append(report.Metrics, compareSamples("RTT", baseRTT, candRTT, &cfg))
+
+		baseStats := Stats{TimeUnit: cfg.TimeUnit, Percentiles: cfg.Percentiles}
+		candStats := Stats{TimeUnit: cfg.TimeUnit, Percentiles: cfg.Percentiles}
+		p.ComputeS3WorkloadStats(&baseStats, cfg.Base, cfg.TimeUnit, false)
+		p.ComputeS3WorkloadStats(&candStats, cfg.Candidate, cfg.TimeUnit, false)
+		if len(baseStats.SeriesS3Workload) == 0 || len(candStats.SeriesS3Workload) == 0 {
+			return nil, errors.New("no workload series for base or candidate mark")
+		}
+		baseAvail := &baseStats.SeriesS3Workload[0]
+		candAvail := &candStats.SeriesS3Workload[0]
+
+		report.Scalars = append(report.Scalars,
+			compareScalars("availability", baseAvail.Availability, candAvail.Availability, true, cfg.AvailThreshold),
+			compareScalars("err_count", float64(baseAvail.ErrCount), float64(candAvail.ErrCount), false,
+				float64(baseAvail.ErrCount)*cfg.Threshold/100))
+		//the time based metrics are measured only on the raw events
+		if baseAvail.RawEvents && candAvail.RawEvents {
+			report.Scalars = append(report.Scalars,
+				compareScalars("time_availability", baseAvail.TimeAvailability, candAvail.TimeAvailability, true, cfg.AvailThreshold),
+				compareScalars("unavailability", baseAvail.Unavailability, candAvail.Unavailability, false,
+					baseAvail.Unavailability*cfg.Threshold/100))
+		}
+	}
+
+	for _, it := range report.Metrics {
+		if it.Regression {
+			report.Regressions = append(report.Regressions, it.Metric)
+		}
+	}
+	for _, it := range report.Scalars {
+		if it.Regression {
+			report.Regressions = append(report.Regressions, it.Metric)
+		}
+	}
+	report.Regression = len(report.Regressions) > 0
+
+	return &report, nil
+}
@@ -0,0 +1,47 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	//expected values of the two-sided test with the normal approximation and
+	//the continuity correction, e.g. scipy.stats.mannwhitneyu(method="asymptotic")
+	tests := []struct {
+		name    string
+		a, b    []float64
+		u, z, p float64
+		wantErr bool
+	}{
+		{"separated", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 0, -2.5067182457620487, 0.012185780355344818, false},
+		{"separated, swapped", []float64{6, 7, 8, 9, 10}, []float64{1, 2, 3, 4, 5}, 25, 2.5067182457620487, 0.012185780355344818, false},
+		{"unequal sizes", []float64{19, 22, 16, 29, 24}, []float64{20, 11, 17, 12}, 17, 1.5921683328090657, 0.11134688653314048, false},
+		{"ties", []float64{1, 2, 2, 3, 4, 5}, []float64{2, 3, 3, 6, 7}, 9, -1.0229289554014698, 0.306341437827711, false},
+		{"identical", []float64{1.1, 2.2, 3.3, 4.4}, []float64{1.1, 2.2, 3.3, 4.4}, 8, 0, 1, false},
+		{"all tied", []float64{1, 1, 1}, []float64{1, 1}, 3, 0, 1, false},
+		{"empty", []float64{}, []float64{1, 2}, 0, 0, 1, true},
+	}
+	const eps = 1e-9
+	for _, tt := range tests {
+		u, z, p, err := MannWhitneyU(tt.a, tt.b)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if math.Abs(u-tt.u) > eps || math.Abs(z-tt.z) > eps || math.Abs(p-tt.p) > eps {
+			t.Errorf("%s: got U=%v z=%v p=%v, want U=%v z=%v p=%v", tt.name, u, z, p, tt.u, tt.z, tt.p)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// findRestartIdxForTs returns the index of the latest restart whose death
+// happened at or before ts; -1 when ts precedes every restart.
+func findRestartIdxForTs(restartEvents []RestartEvent, ts int64) int {
+	return sort.Search(len(restartEvents), func(i int) bool {
+		return restartEvents[i].Death.Ts > ts
+	}) - 1
+}
+
+// restartWindowEnd returns the timestamp where the window of a restart ends:
+// the frontend-up event plus the settle period.
+func restartWindowEnd(evt *RestartEvent) int64 {
+	return evt.StartFrontendUp.Ts + int64(evt.SettlePeriod)*MilliS
+}
+
+// CorrelateS3WorkloadEntries attributes each S3 workload entry to the
+// restart window (death -> frontend-up + settle period) it falls in.
+// Entries outside any window are attributed to the restart id 0.
+func CorrelateS3WorkloadEntries(restartEvents []RestartEvent, evtSeries []S3WorkloadEntry) {
+	for i := range evtSeries {
+		evtSeries[i].RestartId = 0
+		if idx := findRestartIdxForTs(restartEvents, evtSeries[i].Start); idx >= 0 {
+			if evtSeries[i].Start <= restartWindowEnd(&restartEvents[idx]) {
+				evtSeries[i].RestartId = restartEvents[idx].Id
+			}
+		}
+	}
+}
+
+// fillRestartEntriesWithS3WorkloadStats sets, for each restart entry, the
+// statistics of the S3 workload entries attributed to that restart.
+func fillRestartEntriesWithS3WorkloadStats(evtSeries []RestartEntry, s3WLSeries []S3WorkloadEntry) {
+	restartId2Idx := make(map[int]int)
+	for i := range evtSeries {
+		restartId2Idx[evtSeries[i].Id] = i
+	}
+
+	for _, it := range s3WLSeries {
+		if it.RestartId == 0 {
+			continue
+		}
+		if idx, hit := restartId2Idx[it.RestartId]; hit {
+			evtSeries[idx].WLCount++
+			if it.ErrDesc != "" {
+				evtSeries[idx].WLErrCount++
+			}
+			evtSeries[idx].WLMaxRTT = math.Max(evtSeries[idx].WLMaxRTT, it.RTT)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+)
+
+// distSeries is the data of a metric of a mark drawn in a distribution plot.
+type distSeries struct {
+	Mark string
+	Data []float64
+}
+
+// restartDistSeries returns, for the marks having restarts, the to-main,
+// the to-frontend-up and the frontend-up-main-delta durations.
+func (p *Probe) restartDistSeries(marks []string, timeUnit string) (toMain, toFUp, fUpMainDelta []distSeries) {
+	for _, mark := range marks {
+		restartEvents, hit := p.CollectedRestartRelatedData[mark]
+		if !hit || len(restartEvents) == 0 {
+			continue
+		}
+		_,
+			evtSeriesMainData,
+			evtSeriesFrontedUpData,
+			evtSeriesFUpMainDelta := GetSplitDataForSingleRestartRelatedData(restartEvents, StrTimeUnit2TimeUnit[timeUnit])
+
+		toMain = append(toMain, distSeries{Mark: mark, Data: evtSeriesMainData})
+		toFUp = append(toFUp, distSeries{Mark: mark, Data: evtSeriesFrontedUpData})
+		fUpMainDelta = append(fUpMainDelta, distSeries{Mark: mark, Data: evtSeriesFUpMainDelta})
+	}
+	return
+}
+
+// s3WorkloadDistSeries returns the RTT of the marks having raw workload data.
+func (p *Probe) s3WorkloadDistSeries(marks []string, timeUnit string) (rtt []distSeries) {
+	for _, mark := range marks {
+		_, evtSeriesRTTData, hit := p.s3WorkloadEntries(mark, p.CollectedRestartRelatedData[mark], StrTimeUnit2TimeUnit[timeUnit])
+		if !hit || len(evtSeriesRTTData) == 0 {
+			continue
+		}
+		rtt = append(rtt, distSeries{Mark: mark, Data: evtSeriesRTTData})
+	}
+	return
+}
+
+// addCDFCurve adds the empirical CDF of data as a step line;
+// with a log scale the non positive values are left out.
+func addCDFCurve(plt *plot.Plot, data []float64, legend string, color int, dashes int, logScale bool) error {
+	sorted := make([]float64, 0, len(data))
+	for _, val := range data {
+		if !logScale || val > 0 {
+			sorted = append(sorted, val)
+		}
+	}
+	if len(sorted) == 0 {
+		return errors.New("no data")
+	}
+	sort.Float64s(sorted)
+
+	pts := make(plotter.XYs, len(sorted))
+	for i, val := range sorted {
+		pts[i].X = val
+		pts[i].Y = float64(i+1) / float64(len(sorted))
+	}
+
+	line, err := plotter.NewLine(pts)
+	if err != nil {
+		return err
+	}
+	line.StepStyle = plotter.PostStep
+	line.Color = plotutil.Color(color)
+	line.Dashes = plotutil.Dashes(dashes)
+
+	plt.Add(line)
+	plt.Legend.Add(legend, line)
+	return nil
+}
+
+func newCDFPlot(title string, xLabel string, cfg PlotConfig) *plot.Plot {
+	plt := plot.New()
+	plt.Add(plotter.NewGrid())
+
+	plt.Title.Text = title
+	plt.X.Label.Text = xLabel
+	plt.Y.Label.Text = "Cumulative fraction"
+	plt.Y.Min = 0
+	plt.Y.Max = 1
+	plt.Legend.Top = true
+	plt.Legend.Left = true
+	if cfg.CDFLogScale {
+		plt.X.Scale = plot.LogScale{}
+		plt.X.Tick.Marker = plot.LogTicks{Prec: -1}
+	}
+	return plt
+}
+
+// GenerateCDFPlots plots the empirical CDF of the restart durations and,
+// when there is raw workload data, of the workload RTT with the marks overlaid;
+// the plots are named after name.
+func (p *Probe) GenerateCDFPlots(marks []string, name string, timeUnit string, genTS string, cfg PlotConfig) ([]string, error) {
+	fNames := []string{}
+	toMain, toFUp, _ := p.restartDistSeries(marks, timeUnit)
+	rtt := p.s3WorkloadDistSeries(marks, timeUnit)
+	if len(toMain) == 0 && len(rtt) == 0 {
+		Logger.Errorf("GenerateCDFPlots: no series with marks: %s", strings.Join(marks, ","))
+		return nil, errors.New("no series with mark")
+	}
+
+	if len(toMain) > 0 {
+		plt := newCDFPlot("Restart Durations CDF: "+strings.Join(marks, ", "), "Duration: "+timeUnit, cfg)
+		for i := range toMain {
+			if err := addCDFCurve(plt, toMain[i].Data, toMain[i].Mark+"-to-main", i, 1, cfg.CDFLogScale); err != nil {
+				Logger.Errorf("GenerateCDFPlots-to-main: %s", err.Error())
+			}
+			if err := addCDFCurve(plt, toFUp[i].Data, toFUp[i].Mark+"-to-frontend-up", i, 0, cfg.CDFLogScale); err != nil {
+				Logger.Errorf("GenerateCDFPlots-to-frontend-up: %s", err.Error())
+			}
+		}
+
+		fBase := genTS + "_" + name + "_cdf_restart"
+
+		fNamesPlot, err := savePlot(plt, fBase, cfg)
+		fNames = append(fNames, fNamesPlot...)
+		if err != nil {
+			Logger.Errorf("GenerateCDFPlots: Saving plot: %s", fBase)
+			return fNames, err
+		}
+	}
+
+	if len(rtt) > 0 {
+		plt := newCDFPlot("S3 Workload RTT CDF: "+strings.Join(marks, ", "), "RTT: "+timeUnit, cfg)
+		for i := range rtt {
+			if err := addCDFCurve(plt, rtt[i].Data, rtt[i].Mark+"-RTT", i, 0, cfg.CDFLogScale); err != nil {
+				Logger.Errorf("GenerateCDFPlots-RTT: %s", err.Error())
+			}
+		}
+
+		fBase := genTS + "_" + name + "_cdf_S3WL_RTT"
+
+		fNamesPlot, err := savePlot(plt, fBase, cfg)
+		fNames = append(fNames, fNamesPlot...)
+		if err != nil {
+			Logger.Errorf("GenerateCDFPlots: Saving plot: %s", fBase)
+			return fNames, err
+		}
+	}
+
+	return fNames, nil
+}
+
+// newSharedBinsHistogram bins data over [min, max] with the fraction
+// of the samples as weight, so that series of different sizes compare.
+func newSharedBinsHistogram(data []float64, lo float64, hi float64, bins int) *plotter.Histogram {
+	width := (hi - lo) / float64(bins)
+	if width == 0 {
+		width = 1
+	}
+	hist := &plotter.Histogram{Bins: make([]plotter.HistogramBin, bins), Width: width}
+	for i := range hist.Bins {
+		hist.Bins[i].Min = lo + float64(i)*width
+		hist.Bins[i].Max = lo + float64(i+1)*width
+	}
+	for _, val := range data {
+		idx := int((val - lo) / width)
+		if idx >= bins {
+			idx = bins - 1
+		}
+		hist.Bins[idx].Weight += 1 / float64(len(data))
+	}
+	return hist
+}
+
+func translucent(c color.Color) color.Color {
+	r, g, b, _ := c.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 90}
+}
+
+func generateHistogramPlot(series []distSeries, title string, xLabel string, fBase string, cfg PlotConfig) ([]string, error) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, it := range series {
+		for _, val := range it.Data {
+			lo = math.Min(lo, val)
+			hi = math.Max(hi, val)
+		}
+	}
+	if math.IsInf(lo, 0) {
+		return nil, errors.New("no data")
+	}
+
+	plt := plot.New()
+	plt.Add(plotter.NewGrid())
+
+	plt.Title.Text = title
+	plt.X.Label.Text = xLabel
+	plt.Y.Label.Text = "Fraction"
+	plt.Legend.Top = true
+
+	for i, it := range series {
+		if len(it.Data) == 0 {
+			continue
+		}
+		hist := newSharedBinsHistogram(it.Data, lo, hi, cfg.HistBins)
+		hist.FillColor = translucent(plotutil.Color(i))
+		hist.LineStyle = plotter.DefaultLineStyle
+		hist.LineStyle.Color = plotutil.Color(i)
+
+		plt.Add(hist)
+		plt.Legend.Add(it.Mark, hist)
+	}
+
+	return savePlot(plt, fBase, cfg)
+}
+
+// GenerateHistogramPlots plots the binned histograms of the restart durations
+// and of the workload RTT, the marks overlaid over the same bins;
+// the plots are named after name.
+func (p *Probe) GenerateHistogramPlots(marks []string, name string, timeUnit string, genTS string, cfg PlotConfig) ([]string, error) {
+	fNames := []string{}
+	toMain, toFUp, _ := p.restartDistSeries(marks, timeUnit)
+	rtt := p.s3WorkloadDistSeries(marks, timeUnit)
+	if len(toMain) == 0 && len(rtt) == 0 {
+		Logger.Errorf("GenerateHistogramPlots: no series with marks: %s", strings.Join(marks, ","))
+		return nil, errors.New("no series with mark")
+	}
+
+	if cfg.HistBins <= 0 {
+		cfg.HistBins = DefaultPlotConfig().HistBins
+	}
+
+	title := ": " + strings.Join(marks, ", ")
+	for _, it := range []struct {
+		series []distSeries
+		title  string
+		xLabel string
+		suffix string
+	}{
+		{toMain, "Histogram - Main" + title, "Duration: " + timeUnit, "_hist_to_main"},
+		{toFUp, "Histogram - FrontEndUp" + title, "Duration: " + timeUnit, "_hist_to_fup"},
+		{rtt, "Histogram - S3 Workload RTT" + title, "RTT: " + timeUnit, "_hist_S3WL_RTT"},
+	} {
+		if len(it.series) == 0 {
+			continue
+		}
+		fBase := genTS + "_" + name + it.suffix
+		fNamesPlot, err := generateHistogramPlot(it.series, it.title, it.xLabel, fBase, cfg)
+		fNames = append(fNames, fNamesPlot...)
+		if err != nil {
+			Logger.Errorf("GenerateHistogramPlots: Saving plot: %s: %s", fBase, err.Error())
+		}
+	}
+
+	return fNames, nil
+}
@@ -0,0 +1,107 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/montanaflynn/stats"
+)
+
+var DefaultPercentiles = []float64{50, 90, 95, 99}
+
+// DistSummary summarizes the distribution of a series of samples.
+type DistSummary struct {
+	Count         uint                    `json:"count"`
+	Min           float64                 `json:"min"`
+	Max           float64                 `json:"max"`
+	Mean          float64                 `json:"mean"`
+	StdDev        float64                 `json:"std_dev"`
+	Median        float64                 `json:"median"`
+	MAD           float64                 `json:"mad"`                      //median absolute deviation
+	Percentiles   map[string]float64      `json:"percentiles"`              //interpolated
+	PercentilesNR map[string]float64      `json:"percentiles_NR"`           //nearest rank
+	MeanCI        *ConfInterval           `json:"mean_CI,omitempty"`        //bootstrap
+	PercentilesCI map[string]ConfInterval `json:"percentiles_CI,omitempty"` //bootstrap, interpolated percentiles
+}
+
+// PercentileKey returns the key of a percentile in a DistSummary, e.g. p99.9
+func PercentileKey(perc float64) string {
+	return "p" + strconv.FormatFloat(perc, 'f', -1, 64)
+}
+
+// ParsePercentiles parses a comma separated list of percentiles, e.g. 50,90,99.9
+func ParsePercentiles(str string) ([]float64, error) {
+	if str == "" {
+		return DefaultPercentiles, nil
+	}
+	var percentiles []float64
+	for _, token := range strings.Split(str, ",") {
+		perc, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(token), "p"), 64)
+		if err != nil {
+			return nil, err
+		}
+		if perc <= 0 || perc > 100 {
+			return nil, errors.New("percentile out of range (0, 100]: " + token)
+		}
+		percentiles = append(percentiles, perc)
+	}
+	return percentiles, nil
+}
+
+// ComputeDistSummary computes the summary of data for the given percentiles.
+func ComputeDistSummary(data []float64, percentiles []float64) DistSummary {
+	summary := DistSummary{Count: uint(len(data)),
+		Percentiles:   make(map[string]float64),
+		PercentilesNR: make(map[string]float64)}
+
+	if len(data) == 0 {
+		return summary
+	}
+
+	if val, err := stats.Min(data); err == nil {
+		summary.Min = val
+	}
+
+	if val, err := stats.Max(data); err == nil {
+		summary.Max = val
+	}
+
+	if val, err := stats.Mean(data); err == nil {
+		summary.Mean = val
+	}
+
+	if val, err := stats.StandardDeviation(data); err == nil {
+		summary.StdDev = val
+	}
+
+	if val, err := stats.Median(data); err == nil {
+		summary.Median = val
+	}
+
+	if val, err := stats.MedianAbsoluteDeviation(data); err == nil {
+		summary.MAD = val
+	}
+
+	for _, perc := range percentiles {
+		if val, err := stats.Percentile(data, perc); err == nil {
+			summary.Percentiles[PercentileKey(perc)] = val
+		}
+		if val, err := stats.PercentileNearestRank(data, perc); err == nil {
+			summary.PercentilesNR[PercentileKey(perc)] = val
+		}
+	}
+
+	return summary
+}
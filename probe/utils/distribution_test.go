@@ -0,0 +1,46 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePercentiles(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    []float64
+		wantErr bool
+	}{
+		{"", DefaultPercentiles, false},
+		{"50,90,99", []float64{50, 90, 99}, false},
+		{"p50, p99.9", []float64{50, 99.9}, false},
+		{"100", []float64{100}, false},
+		{"0.1", []float64{0.1}, false},
+		{"0", nil, true},
+		{"-5", nil, true},
+		{"100.1", nil, true},
+		{"50,", nil, true},
+		{"abc", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePercentiles(tt.str)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePercentiles(%q) error = %v, wantErr %v", tt.str, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePercentiles(%q) = %v, want %v", tt.str, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// RestartRow is a row of the raw restart series export, durations in TimeUnit.
+type RestartRow struct {
+	Mark         string  `parquet:"name=mark, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RunId        string  `parquet:"name=run_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RestartId    int64   `parquet:"name=restart_id, type=INT64"`
+	Node         string  `parquet:"name=node, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeathType    string  `parquet:"name=death_type, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeathTs      int64   `parquet:"name=death_ts, type=INT64"`
+	TimeUnit     string  `parquet:"name=time_unit, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ToMain       float64 `parquet:"name=duration_to_main, type=DOUBLE"`
+	ToFrontendUp float64 `parquet:"name=duration_to_frontend_up, type=DOUBLE"`
+	FUpMainDelta float64 `parquet:"name=frontend_up_main_delta, type=DOUBLE"`
+	WLCount      int64   `parquet:"name=wl_count, type=INT64"`
+	WLErrCount   int64   `parquet:"name=wl_err_count, type=INT64"`
+	WLMaxRTT     float64 `parquet:"name=wl_max_RTT, type=DOUBLE"`
+	Outlier      bool    `parquet:"name=outlier, type=BOOLEAN"`
+}
+
+func (row *RestartRow) csvHeader() []string {
+	return []string{"mark", "run_id", "restart_id", "node", "death_type", "death_ts", "time_unit",
+		"duration_to_main", "duration_to_frontend_up", "frontend_up_main_delta",
+		"wl_count", "wl_err_count", "wl_max_RTT", "outlier"}
+}
+
+func (row *RestartRow) csvRecord() []string {
+	return []string{row.Mark, row.RunId, strconv.FormatInt(row.RestartId, 10), row.Node, row.DeathType,
+		strconv.FormatInt(row.DeathTs, 10), row.TimeUnit,
+		formatFloat(row.ToMain), formatFloat(row.ToFrontendUp), formatFloat(row.FUpMainDelta),
+		strconv.FormatInt(row.WLCount, 10), strconv.FormatInt(row.WLErrCount, 10), formatFloat(row.WLMaxRTT),
+		strconv.FormatBool(row.Outlier)}
+}
+
+// S3WorkloadRow is a row of the raw S3 workload series export, RTT in TimeUnit;
+// node and death type are the ones of the restart the entry is attributed to.
+type S3WorkloadRow struct {
+	Mark       string  `parquet:"name=mark, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RunId      string  `parquet:"name=run_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	WLId       int64   `parquet:"name=wl_id, type=INT64"`
+	RestartId  int64   `parquet:"name=restart_id, type=INT64"`
+	Node       string  `parquet:"name=node, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeathType  string  `parquet:"name=death_type, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Start      int64   `parquet:"name=start, type=INT64"`
+	End        int64   `parquet:"name=end, type=INT64"`
+	Size       int64   `parquet:"name=size, type=INT64"`
+	TimeUnit   string  `parquet:"name=time_unit, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RTT        float64 `parquet:"name=RTT, type=DOUBLE"`
+	Retries    int64   `parquet:"name=retries, type=INT64"`
+	ErrCode    string  `parquet:"name=err_code, type=BYTE_ARRAY, convertedtype=UTF8"`
+	HTTPStatus int64   `parquet:"name=http_status, type=INT64"`
+	ErrCat     string  `parquet:"name=err_category, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ErrDesc    string  `parquet:"name=err_desc, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func (row *S3WorkloadRow) csvHeader() []string {
+	return []string{"mark", "run_id", "wl_id", "restart_id", "node", "death_type", "start", "end", "size",
+		"time_unit", "RTT", "retries", "err_code", "http_status", "err_category", "err_desc"}
+}
+
+func (row *S3WorkloadRow) csvRecord() []string {
+	return []string{row.Mark, row.RunId, strconv.FormatInt(row.WLId, 10), strconv.FormatInt(row.RestartId, 10),
+		row.Node, row.DeathType, strconv.FormatInt(row.Start, 10), strconv.FormatInt(row.End, 10),
+		strconv.FormatInt(row.Size, 10), row.TimeUnit, formatFloat(row.RTT), strconv.FormatInt(row.Retries, 10),
+		row.ErrCode, strconv.FormatInt(row.HTTPStatus, 10), row.ErrCat, row.ErrDesc}
+}
+
+type csvRow interface {
+	csvHeader() []string
+	csvRecord() []string
+}
+
+func formatFloat(val float64) string {
+	return strconv.FormatFloat(val, 'f', -1, 64)
+}
+
+// RawSeriesRows returns the rows of the raw series dumped in the stats.
+func RawSeriesRows(stats *Stats) ([]RestartRow, []S3WorkloadRow) {
+	var restartRows []RestartRow
+	var s3WLRows []S3WorkloadRow
+
+	type restartKey struct {
+		mark string
+		id   int
+	}
+	restarts := make(map[restartKey]*RestartEntry)
+
+	for _, series := range stats.SeriesRestart {
+		for i := range series.Data {
+			it := &series.Data[i]
+			restarts[restartKey{series.Mark, it.Id}] = it
+			restartRows = append(restartRows, RestartRow{Mark: series.Mark,
+				RunId:        it.RunId,
+				RestartId:    int64(it.Id),
+				Node:         it.Node,
+				DeathType:    it.DeathType,
+				DeathTs:      it.DeathTs,
+				TimeUnit:     stats.TimeUnit,
+				ToMain:       it.RestartDurationToMain,
+				ToFrontendUp: it.RestartDurationToFrontendUp,
+				FUpMainDelta: it.FUpMainDelta,
+				WLCount:      int64(it.WLCount),
+				WLErrCount:   int64(it.WLErrCount),
+				WLMaxRTT:     it.WLMaxRTT,
+				Outlier:      it.Outlier})
+		}
+	}
+
+	for _, series := range stats.SeriesS3Workload {
+		for i := range series.Data {
+			it := &series.Data[i]
+			row := S3WorkloadRow{Mark: series.Mark,
+				RunId:      it.RunId,
+				WLId:       int64(it.Id),
+				RestartId:  int64(it.RestartId),
+				Start:      it.Start,
+				End:        it.End,
+				Size:       it.Size,
+				TimeUnit:   stats.TimeUnit,
+				RTT:        it.RTT,
+				Retries:    int64(it.Retries),
+				ErrCode:    it.ErrCode,
+				HTTPStatus: int64(it.HTTPStatus),
+				ErrCat:     it.ErrCat,
+				ErrDesc:    it.ErrDesc}
+			if restart, hit := restarts[restartKey{series.Mark, it.RestartId}]; hit {
+				row.Node = restart.Node
+				row.DeathType = restart.DeathType
+			}
+			s3WLRows = append(s3WLRows, row)
+		}
+	}
+
+	return restartRows, s3WLRows
+}
+
+func writeCSV[T any, PT interface {
+	*T
+	csvRow
+}](fName string, rows []T) error {
+	file, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if err := w.Write(PT(new(T)).csvHeader()); err != nil {
+		return err
+	}
+	for i := range rows {
+		if err := w.Write(PT(&rows[i]).csvRecord()); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeParquet[T any](fName string, rows []T) error {
+	file, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	pw, err := writer.NewParquetWriterFromWriter(file, new(T), 1)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if err := pw.Write(rows[i]); err != nil {
+			return err
+		}
+	}
+	return pw.WriteStop()
+}
+
+// ExportRawSeries writes the raw series dumped in the stats as CSV and Parquet files.
+func (p *Probe) ExportRawSeries(genTS string, name string, stats *Stats) []string {
+	fNames := []string{}
+	restartRows, s3WLRows := RawSeriesRows(stats)
+
+	if len(restartRows) > 0 {
+		fBase := genTS + "_" + name + "_restarts"
+		if err := writeCSV(fBase+".csv", restartRows); err == nil {
+			fNames = append(fNames, fBase+".csv")
+		} else {
+			Logger.Errorf("ExportRawSeries: writeCSV:%s", err.Error())
+		}
+		if err := writeParquet(fBase+".parquet", restartRows); err == nil {
+			fNames = append(fNames, fBase+".parquet")
+		} else {
+			Logger.Errorf("ExportRawSeries: writeParquet:%s", err.Error())
+		}
+	}
+
+	if len(s3WLRows) > 0 {
+		fBase := genTS + "_" + name + "_S3WL"
+		if err := writeCSV(fBase+".csv", s3WLRows); err == nil {
+			fNames = append(fNames, fBase+".csv")
+		} else {
+			Logger.Errorf("ExportRawSeries: writeCSV:%s", err.Error())
+		}
+		if err := writeParquet(fBase+".parquet", s3WLRows); err == nil {
+			fNames = append(fNames, fBase+".parquet")
+		} else {
+			Logger.Errorf("ExportRawSeries: writeParquet:%s", err.Error())
+		}
+	}
+
+	return fNames
+}
@@ -0,0 +1,246 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	FillOpPut   = "put"
+	FillOpErase = "erase"
+)
+
+type FillConfig struct {
+	Client        *s3.S3 `json:"-"`
+	BucketName    string
+	ObjBaseName   string
+	PayloadSpec   *PayloadSpec
+	StartIdx      uint64 //index of the first object
+	ObjCount      uint64
+	AddTS         bool
+	TS            int64 //unix time appended to the names with AddTS, set once at parse time
+	Concurrency   uint
+	ProgressEvery uint64 //log the progress every n objects, 0 means every 10%
+}
+
+func (cfg *FillConfig) objName(idx uint64) string {
+	if cfg.AddTS {
+		return cfg.ObjBaseName + "_" + strconv.FormatUint(idx, 10) + "_" + strconv.FormatInt(cfg.TS, 10)
+	}
+	return cfg.ObjBaseName + "_" + strconv.FormatUint(idx, 10)
+}
+
+// ParseFillConfig builds a FillConfig reading its parameters with getPar;
+// the returned flag tells whether the objects should be erased after the fill.
+func ParseFillConfig(getPar func(string) string) (*FillConfig, bool, error) {
+	cfg := FillConfig{Client: S3Client_S3GW,
+		BucketName:  getPar("bucket"),
+		ObjBaseName: getPar("obj_base_name"),
+		AddTS:       getPar("add-ts") == "1",
+		Concurrency: 1}
+	erase := getPar("erase") == "1"
+	if cfg.AddTS {
+		cfg.TS = time.Now().Unix()
+	}
+
+	var err error
+	if cfg.PayloadSpec, err = ParsePayloadSpec(getPar); err != nil {
+		return nil, false, err
+	}
+
+	if cfg.ObjCount, err = strconv.ParseUint(getPar("obj_count"), 0, 64); err != nil {
+		return nil, false, err
+	}
+
+	if val, err := strconv.ParseUint(getPar("start_idx"), 0, 64); err == nil {
+		cfg.StartIdx = val
+	}
+
+	if val, err := strconv.ParseUint(getPar("concurrency"), 0, 32); err == nil && val > 0 {
+		cfg.Concurrency = uint(val)
+	}
+
+	if val, err := strconv.ParseUint(getPar("progress"), 0, 64); err == nil {
+		cfg.ProgressEvery = val
+	}
+
+	return &cfg, erase, nil
+}
+
+var ErrFillRunning = errors.New("fill already running")
+
+// RunFill fills the bucket and/or erases the objects afterwards;
+// only one fill runs at a time, ErrFillRunning is returned otherwise.
+func RunFill(cfg *FillConfig, fill bool, erase bool) ([]FillSummary, error) {
+	if !FillPrg.Running.CompareAndSwap(false, true) {
+		return nil, ErrFillRunning
+	}
+	defer FillPrg.Running.Store(false)
+
+	summaries := []FillSummary{}
+	if fill {
+		summaries = append(summaries, fillWithObjects(cfg))
+	}
+	if erase {
+		summaries = append(summaries, eraseObjects(cfg))
+	}
+	return summaries, nil
+}
+
+// FillProgress tracks a fill/erase operation while it runs.
+type FillProgress struct {
+	Running  atomic.Bool
+	Op       atomic.Value //string
+	Total    atomic.Uint64
+	Done     atomic.Uint64
+	Failures atomic.Uint64
+	Bytes    atomic.Int64
+	StartTs  atomic.Int64
+}
+
+// fill progress
+var FillPrg FillProgress
+
+func (prg *FillProgress) reset(op string, total uint64) {
+	prg.Op.Store(op)
+	prg.Total.Store(total)
+	prg.Done.Store(0)
+	prg.Failures.Store(0)
+	prg.Bytes.Store(0)
+	prg.StartTs.Store(time.Now().UnixNano())
+}
+
+// Summary returns a snapshot of the progress.
+func (prg *FillProgress) Summary() FillSummary {
+	op, _ := prg.Op.Load().(string)
+	summary := FillSummary{Op: op,
+		Running:  prg.Running.Load(),
+		Total:    prg.Total.Load(),
+		Done:     prg.Done.Load(),
+		Failures: prg.Failures.Load(),
+		Bytes:    prg.Bytes.Load()}
+	if startTs := prg.StartTs.Load(); startTs > 0 {
+		summary.setThroughput(time.Duration(time.Now().UnixNano() - startTs))
+	}
+	return summary
+}
+
+type FillSummary struct {
+	Op               string          `json:"op"`
+	Running          bool            `json:"running"`
+	BucketName       string          `json:"bucket"`
+	Total            uint64          `json:"total"`
+	Done             uint64          `json:"done"`
+	Failures         uint64          `json:"failures"`
+	Bytes            int64           `json:"bytes"`
+	Duration         float64         `json:"duration_s"`
+	ObjThroughput    float64         `json:"objects_per_s"`
+	BytesThroughput  float64         `json:"bytes_per_s"`
+	FailuresByErrCat map[string]uint `json:"failures_by_err_category,omitempty"`
+}
+
+func (summary *FillSummary) setThroughput(elapsed time.Duration) {
+	summary.Duration = elapsed.Seconds()
+	if summary.Duration > 0 {
+		summary.ObjThroughput = float64(summary.Done-summary.Failures) / summary.Duration
+		summary.BytesThroughput = float64(summary.Bytes) / summary.Duration
+	}
+}
+
+// runObjectsOp applies op to ObjCount objects using Concurrency workers,
+// reporting the progress in FillPrg.
+func runObjectsOp(cfg *FillConfig, opName string, op func(objName string) (int64, error)) FillSummary {
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 1
+	}
+	progressEvery := cfg.ProgressEvery
+	if progressEvery == 0 {
+		progressEvery = cfg.ObjCount / 10
+		if progressEvery == 0 {
+			progressEvery = 1
+		}
+	}
+
+	FillPrg.reset(opName, cfg.ObjCount)
+
+	var mtx sync.Mutex
+	failuresByErrCat := make(map[string]uint)
+
+	idxChan := make(chan uint64, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for w := uint(0); w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range idxChan {
+				bytes, err := op(cfg.objName(idx))
+				if err != nil {
+					FillPrg.Failures.Add(1)
+					errCode, httpStatus := GetErrorCode(err)
+					mtx.Lock()
+					failuresByErrCat[ClassifyError(err, errCode, httpStatus)]++
+					mtx.Unlock()
+				} else {
+					FillPrg.Bytes.Add(bytes)
+				}
+				if done := FillPrg.Done.Add(1); done%progressEvery == 0 {
+					Logger.Infof("%s progress: %d/%d, failures:%d", opName, done, cfg.ObjCount, FillPrg.Failures.Load())
+				}
+			}
+		}()
+	}
+
+	start := time.Now()
+	for idx := cfg.StartIdx; idx < cfg.StartIdx+cfg.ObjCount; idx++ {
+		idxChan <- idx
+	}
+	close(idxChan)
+	wg.Wait()
+
+	summary := FillSummary{Op: opName,
+		BucketName:       cfg.BucketName,
+		Total:            cfg.ObjCount,
+		Done:             FillPrg.Done.Load(),
+		Failures:         FillPrg.Failures.Load(),
+		Bytes:            FillPrg.Bytes.Load(),
+		FailuresByErrCat: failuresByErrCat}
+	summary.setThroughput(time.Since(start))
+
+	Logger.Infof("%s completed: objects:%d, failures:%d, bytes:%d, duration:%.3fs, %.1f obj/s",
+		opName, summary.Done, summary.Failures, summary.Bytes, summary.Duration, summary.ObjThroughput)
+
+	return summary
+}
+
+// fillWithObjects puts ObjCount objects in the bucket, creating the bucket if needed.
+func fillWithObjects(cfg *FillConfig) FillSummary {
+	CreateBucket(cfg.Client, cfg.BucketName)
+	return runObjectsOp(cfg, FillOpPut, func(objName string) (int64, error) {
+		payload, size := cfg.PayloadSpec.NewReader()
+		_, _, _, err := SendObject(cfg.Client, cfg.BucketName, objName, payload)
+		return size, err
+	})
+}
+
+// eraseObjects deletes ObjCount objects from the bucket.
+func eraseObjects(cfg *FillConfig) FillSummary {
+	return runObjectsOp(cfg, FillOpErase, func(objName string) (int64, error) {
+		return 0, EraseObject(cfg.Client, cfg.BucketName, objName)
+	})
+}
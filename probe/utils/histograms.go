@@ -0,0 +1,177 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Latencies are recorded in nanoseconds, from 1us up to 1 hour,
+// with 3 significant digits: the memory used by a histogram is bounded
+// regardless of the number of recorded values.
+const (
+	hdrLowestNs  = MicroS
+	hdrHighestNs = 3600 * Sec
+	hdrSigFigs   = 3
+)
+
+const (
+	S3OpPutObject = "PutObject"
+	S3OpUnknown   = "unknown" //imported entries saved without their operation
+)
+
+// S3WorkloadHistograms holds the latency histograms per mark and per operation.
+type S3WorkloadHistograms map[string]map[string]*hdrhistogram.Histogram
+
+func NewLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(hdrLowestNs, hdrHighestNs, hdrSigFigs)
+}
+
+// Record records a latency expressed in nanoseconds.
+func (hists S3WorkloadHistograms) Record(mark string, op string, rttNs int64) {
+	if hists[mark] == nil {
+		hists[mark] = make(map[string]*hdrhistogram.Histogram)
+	}
+	hist, hit := hists[mark][op]
+	if !hit {
+		hist = NewLatencyHistogram()
+		hist.SetTag(mark + "/" + op)
+		hist.SetStartTimeMs(time.Now().UnixMilli())
+		hists[mark][op] = hist
+	}
+	hist.SetEndTimeMs(time.Now().UnixMilli())
+
+	if rttNs > hdrHighestNs {
+		rttNs = hdrHighestNs
+	}
+	if err := hist.RecordValue(rttNs); err != nil {
+		Logger.Errorf("RecordValue: %s", err.Error())
+	}
+}
+
+// S3WorkloadErrorCounts holds the error counts per mark and per error category,
+// collected alongside the histograms so that they do not need the raw events.
+type S3WorkloadErrorCounts map[string]map[string]uint
+
+func (counts S3WorkloadErrorCounts) Record(mark string, errCat string) {
+	if counts[mark] == nil {
+		counts[mark] = make(map[string]uint)
+	}
+	counts[mark][errCat]++
+}
+
+// Merged returns the merge of the histograms of the given marks for the
+// operation; all the operations are merged when op is empty.
+func (hists S3WorkloadHistograms) Merged(marks []string, op string) *hdrhistogram.Histogram {
+	merged := NewLatencyHistogram()
+	for _, mark := range marks {
+		for histOp, hist := range hists[mark] {
+			if op == "" || op == histOp {
+				merged.Merge(hist)
+			}
+		}
+	}
+	return merged
+}
+
+// Ops returns the sorted list of the operations recorded for a mark.
+func (hists S3WorkloadHistograms) Ops(mark string) []string {
+	var ops []string
+	for op := range hists[mark] {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// ComputeDistSummaryFromHistogram computes the summary of a latency histogram,
+// values are expressed in timeUnit. The MAD is not available from a histogram.
+func ComputeDistSummaryFromHistogram(hist *hdrhistogram.Histogram, percentiles []float64, timeUnit int64) DistSummary {
+	summary := DistSummary{Count: uint(hist.TotalCount()),
+		Percentiles:   make(map[string]float64),
+		PercentilesNR: make(map[string]float64)}
+
+	if hist.TotalCount() == 0 {
+		return summary
+	}
+
+	toTimeUnit := func(valNs float64) float64 {
+		return valNs / float64(timeUnit)
+	}
+
+	summary.Min = toTimeUnit(float64(hist.Min()))
+	summary.Max = toTimeUnit(float64(hist.Max()))
+	summary.Mean = toTimeUnit(hist.Mean())
+	summary.StdDev = toTimeUnit(hist.StdDev())
+	summary.Median = toTimeUnit(float64(hist.ValueAtQuantile(50)))
+
+	for _, perc := range percentiles {
+		val := toTimeUnit(float64(hist.ValueAtQuantile(perc)))
+		summary.Percentiles[PercentileKey(perc)] = val
+		summary.PercentilesNR[PercentileKey(perc)] = val
+	}
+
+	return summary
+}
+
+// WriteHistogramLog exports the histograms in the HdrHistogram log format.
+func WriteHistogramLog(w io.Writer, hists []*hdrhistogram.Histogram) error {
+	writer := hdrhistogram.NewHistogramLogWriter(w)
+	if err := writer.OutputLogFormatVersion(); err != nil {
+		return err
+	}
+	if err := writer.OutputComment("s3gw-probe S3 workload latencies, values in nanoseconds"); err != nil {
+		return err
+	}
+	if err := writer.OutputLegend(); err != nil {
+		return err
+	}
+	for _, hist := range hists {
+		if err := writer.OutputIntervalHistogram(hist); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveS3WorkloadHistogramLog writes the histograms of the mark to a log file.
+func (p *Probe) SaveS3WorkloadHistogramLog(mark string, genTS string) (string, error) {
+	//copied as the workload keeps recording
+	var hists []*hdrhistogram.Histogram
+	p.S3WorkloadMtx.Lock()
+	for _, op := range p.CollectedS3WorkloadHistograms.Ops(mark) {
+		hists = append(hists, hdrhistogram.Import(p.CollectedS3WorkloadHistograms[mark][op].Export()))
+	}
+	p.S3WorkloadMtx.Unlock()
+	if len(hists) == 0 {
+		return "", nil
+	}
+
+	fName := genTS + "_" + mark + "_S3WL_RTT" + ".hlog"
+	file, err := os.Create(fName)
+	if err != nil {
+		Logger.Errorf("os.Create:%s", err.Error())
+		return "", err
+	}
+	defer file.Close()
+
+	if err := WriteHistogramLog(file, hists); err != nil {
+		Logger.Errorf("WriteHistogramLog:%s", err.Error())
+		return "", err
+	}
+	return fName, nil
+}
@@ -0,0 +1,213 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+const statsObjSuffix = "_stats.json"
+
+// HistoryEntry is a stats object saved by a previous run in the save-data bucket.
+type HistoryEntry struct {
+	Key          string   `json:"key"`
+	Ts           string   `json:"ts"`
+	Mark         string   `json:"mark"`
+	Size         int64    `json:"size"`
+	LastModified int64    `json:"last_modified"`
+	ImportedAs   []string `json:"imported_as"`
+}
+
+// ListHistory lists the stats objects in the save-data bucket, newest first.
+func (p *Probe) ListHistory(prefix string) ([]HistoryEntry, error) {
+	objects, err := ListObjects(S3Client_SaveData, Cfg.SaveDataBucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []HistoryEntry{}
+	for _, obj := range objects {
+		key := *obj.Key
+		if !strings.HasSuffix(key, statsObjSuffix) {
+			continue
+		}
+		entry := HistoryEntry{Key: key, Size: *obj.Size, LastModified: obj.LastModified.Unix()}
+		if tokens := strings.SplitN(strings.TrimSuffix(key, statsObjSuffix), "_", 2); len(tokens) == 2 {
+			entry.Ts, entry.Mark = tokens[0], tokens[1]
+		}
+		for mark, source := range p.ImportedMarks {
+			if source == key {
+				entry.ImportedAs = append(entry.ImportedAs, mark)
+			}
+		}
+		sort.Strings(entry.ImportedAs)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key > entries[j].Key })
+	return entries, nil
+}
+
+// IsImportedMark returns whether the mark holds historical, read-only, data.
+func (p *Probe) IsImportedMark(mark string) bool {
+	_, hit := p.ImportedMarks[mark]
+	return hit
+}
+
+// ImportHistory loads the series of a stats object saved with their full data,
+// each one as the read-only mark <ts>_<series mark>.
+func (p *Probe) ImportHistory(key string) ([]string, error) {
+	body, err := GetObject(S3Client_SaveData, Cfg.SaveDataBucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats Stats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return nil, err
+	}
+	tu, hit := StrTimeUnit2TimeUnit[stats.TimeUnit]
+	if !hit {
+		return nil, errors.New("unknown time unit: " + stats.TimeUnit)
+	}
+
+	ts := strings.SplitN(key, "_", 2)[0]
+	toMark := func(mark string) string { return ts + "_" + mark }
+
+	for _, series := range stats.SeriesRestart {
+		if _, hit := p.CollectedRestartRelatedData[toMark(series.Mark)]; hit {
+			return nil, errors.New("mark already present: " + toMark(series.Mark))
+		}
+	}
+	p.S3WorkloadMtx.Lock()
+	for _, series := range stats.SeriesS3Workload {
+		if _, hit := p.CollectedS3WorkloadHistograms[toMark(series.Mark)]; hit {
+			p.S3WorkloadMtx.Unlock()
+			return nil, errors.New("mark already present: " + toMark(series.Mark))
+		}
+	}
+	p.S3WorkloadMtx.Unlock()
+
+	s3WLSeries := make(map[string][]S3WorkloadEntry)
+	for _, series := range stats.SeriesS3Workload {
+		s3WLSeries[series.Mark] = series.Data
+	}
+
+	var marks []string
+	imported := make(map[string]bool)
+	for _, series := range stats.SeriesRestart {
+		if len(series.Data) == 0 {
+			Logger.Warnf("IMPORT - series %s of %s without data, skipped", series.Mark, key)
+			continue
+		}
+		mark := toMark(series.Mark)
+		p.CollectedRestartRelatedData[mark] = restartEventsFromEntries(series.Data, s3WLSeries[series.Mark], tu)
+		p.importS3WorkloadEntries(mark, s3WLSeries[series.Mark])
+
+		imported[series.Mark] = true
+		p.ImportedMarks[mark] = key
+		marks = append(marks, mark)
+	}
+
+	//workload series without restarts, e.g. a baseline run
+	for _, series := range stats.SeriesS3Workload {
+		if imported[series.Mark] {
+			continue
+		}
+		if len(series.Data) == 0 {
+			Logger.Warnf("IMPORT - workload series %s of %s without data, skipped", series.Mark, key)
+			continue
+		}
+		mark := toMark(series.Mark)
+		p.importS3WorkloadEntries(mark, series.Data)
+
+		p.ImportedMarks[mark] = key
+		marks = append(marks, mark)
+	}
+
+	Logger.Infof("IMPORT - %s as %v", key, marks)
+	return marks, nil
+}
+
+// restartEventsFromEntries rebuilds the restart events of a saved series;
+// a death timestamp not saved (older runs) is approximated with the start
+// of the first workload entry attributed to the restart or, without any,
+// by laying the restarts one after the other.
+func restartEventsFromEntries(entries []RestartEntry, wlData []S3WorkloadEntry, timeUnit int64) []RestartEvent {
+	firstWLStart := make(map[int]int64)
+	for _, it := range wlData {
+		if start, hit := firstWLStart[it.RestartId]; it.RestartId > 0 && (!hit || it.Start < start) {
+			firstWLStart[it.RestartId] = it.Start
+		}
+	}
+
+	var restartEvents []RestartEvent
+	var lastEnd int64
+	for _, it := range entries {
+		deathTs := it.DeathTs
+		if deathTs == 0 {
+			if start, hit := firstWLStart[it.Id]; hit {
+				deathTs = start
+			} else {
+				deathTs = lastEnd
+			}
+		}
+		evt := RestartEvent{Id: it.Id,
+			RunId:           it.RunId,
+			Death:           &DeathEvent{Ts: deathTs},
+			StartMain:       &StartEvent{Ts: deathTs + int64(it.RestartDurationToMain*float64(timeUnit)), Where: "main"},
+			StartFrontendUp: &StartEvent{Ts: deathTs + int64(it.RestartDurationToFrontendUp*float64(timeUnit)), Where: "frontend-up"},
+			SettlePeriod:    Cfg.SettleMSecsAfterFrontendUp,
+			Node:            it.Node,
+			How:             it.DeathType}
+		lastEnd = restartWindowEnd(&evt)
+		restartEvents = append(restartEvents, evt)
+	}
+	return restartEvents
+}
+
+// importS3WorkloadEntries collects the saved workload entries as the raw events
+// of the mark; entries saved without their operation are recorded as unknown.
+func (p *Probe) importS3WorkloadEntries(mark string, entries []S3WorkloadEntry) {
+	p.S3WorkloadMtx.Lock()
+	defer p.S3WorkloadMtx.Unlock()
+	for i := range entries {
+		op := entries[i].Op
+		if op == "" {
+			op = S3OpUnknown
+		}
+		evt := s3WorkloadEventFromEntry(&entries[i])
+		p.collectS3WorkloadEvent(mark, op, &evt, true)
+	}
+}
+
+func s3WorkloadEventFromEntry(entry *S3WorkloadEntry) S3WorkloadEvent {
+	evt := S3WorkloadEvent{Id: entry.Id,
+		RunId:      entry.RunId,
+		StartTs:    entry.Start,
+		EndTs:      entry.End,
+		Size:       entry.Size,
+		Retries:    entry.Retries,
+		ErrCode:    entry.ErrCode,
+		HTTPStatus: entry.HTTPStatus,
+		ErrCat:     entry.ErrCat}
+	if entry.ErrDesc != "" {
+		evt.Error = errors.New(entry.ErrDesc)
+		if evt.ErrCat == ErrCatNone {
+			//saved before the entries carried their category
+			evt.ErrCat = ClassifyError(evt.Error, entry.ErrCode, entry.HTTPStatus)
+		}
+	}
+	return evt
+}
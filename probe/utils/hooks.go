@@ -0,0 +1,331 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	HookPhasePreDeath       = "pre-death" //between a frontend-up and the next death
+	HookPhasePostFrontendUp = "post-fup"  //right after a restart has been collected
+	HookPhaseLieDown        = "lie-down"  //while the deployment is scaled to 0
+)
+
+const HookActionSweepStep = "sweep-step"
+
+// HookAction is an action that can be scheduled in a restart cycle.
+type HookAction func(p *Probe, args map[string]string) error
+
+// Hook schedules an action in a phase of the restart cycle.
+type Hook struct {
+	Phase  string            `json:"phase"`
+	Action string            `json:"action"`
+	Args   map[string]string `json:"args"`
+	Every  uint              `json:"every"` //run every n restarts, 0 and 1 mean always
+}
+
+var hookActions = map[string]HookAction{}
+
+// internalHookActions are scheduled by the probe itself, not by the users.
+var internalHookActions = map[string]HookAction{
+	HookActionSweepStep: hookSweepStep,
+}
+
+func init() {
+	RegisterHookAction("fill", hookFill)
+	RegisterHookAction("erase", hookErase)
+	RegisterHookAction("s3-burst", hookS3Burst)
+	RegisterHookAction("set-taint", hookSetTaint)
+	RegisterHookAction("unset-taint", hookUnsetTaint)
+	RegisterHookAction("scale", hookScale)
+	RegisterHookAction("wait", hookWait)
+	RegisterHookAction("webhook", hookWebhook)
+}
+
+func RegisterHookAction(name string, action HookAction) {
+	hookActions[name] = action
+}
+
+func HookActionNames() []string {
+	var names []string
+	for name := range hookActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupHookAction(name string) (HookAction, bool) {
+	if action, hit := hookActions[name]; hit {
+		return action, true
+	}
+	action, hit := internalHookActions[name]
+	return action, hit
+}
+
+// ParseHook parses a hook in the form: <phase>:<action>[:<key>=<val>,<key>=<val>...]
+// an optional every=<n> argument makes the hook run every n restarts.
+func ParseHook(str string) (*Hook, error) {
+	if strings.Contains(str, ";") {
+		return nil, errors.New("malformed hook, arguments are separated by ',': " + str)
+	}
+
+	tokens := strings.SplitN(str, ":", 3)
+	if len(tokens) < 2 {
+		return nil, errors.New("malformed hook: " + str)
+	}
+
+	hook := Hook{Phase: tokens[0], Action: tokens[1], Args: make(map[string]string)}
+
+	switch hook.Phase {
+	case HookPhasePreDeath, HookPhasePostFrontendUp, HookPhaseLieDown:
+	default:
+		return nil, errors.New("unknown hook phase: " + hook.Phase)
+	}
+
+	if _, hit := hookActions[hook.Action]; !hit {
+		return nil, errors.New("unknown hook action: " + hook.Action)
+	}
+
+	if len(tokens) == 3 && tokens[2] != "" {
+		for _, parVal := range strings.Split(tokens[2], ",") {
+			kv := strings.SplitN(parVal, "=", 2)
+			if len(kv) != 2 {
+				return nil, errors.New("malformed hook argument: " + parVal)
+			}
+			hook.Args[kv[0]] = kv[1]
+		}
+	}
+
+	if every, hit := hook.Args["every"]; hit {
+		if val, err := strconv.ParseUint(every, 0, 32); err == nil {
+			hook.Every = uint(val)
+		} else {
+			return nil, err
+		}
+	}
+
+	return &hook, nil
+}
+
+// RunHooks runs, in order, the hooks scheduled for the phase.
+func (p *Probe) RunHooks(phase string) {
+	for _, hook := range p.CurrentHooks {
+		if hook.Phase != phase {
+			continue
+		}
+		if hook.Every > 1 && p.CurrentId%int(hook.Every) != 0 {
+			continue
+		}
+		Logger.Infof("HOOK - %s:%s ...", phase, hook.Action)
+		endSpan := p.traceSpan("hook:" + phase + ":" + hook.Action)
+		action, _ := lookupHookAction(hook.Action)
+		err := action(p, hook.Args)
+		if err != nil {
+			Logger.Errorf("hook %s:%s: %s", phase, hook.Action, err.Error())
+		}
+		endSpan(err)
+	}
+}
+
+func argOrDefault(args map[string]string, key string, def string) string {
+	if val, hit := args[key]; hit && val != "" {
+		return val
+	}
+	return def
+}
+
+func hookFill(p *Probe, args map[string]string) error {
+	cfg, erase, err := ParseFillConfig(func(key string) string { return args[key] })
+	if err != nil {
+		return err
+	}
+	_, err = RunFill(cfg, true, erase)
+	return err
+}
+
+func hookErase(p *Probe, args map[string]string) error {
+	cfg, _, err := ParseFillConfig(func(key string) string { return args[key] })
+	if err != nil {
+		return err
+	}
+	_, err = RunFill(cfg, false, true)
+	return err
+}
+
+// BurstMark returns the mark of the series collecting the puts of the
+// s3-burst hook of a mark, kept apart from the workload's series so that
+// they do not weigh on its RTT, errors and availability.
+func BurstMark(mark string) string {
+	return mark + "_burst"
+}
+
+// hookS3Burst sends count objects recording them as S3 workload events
+// in the burst series of the current mark.
+func hookS3Burst(p *Probe, args map[string]string) error {
+	count, err := strconv.ParseUint(argOrDefault(args, "count", "1"), 0, 32)
+	if err != nil {
+		return err
+	}
+	payloadSpec, err := ParsePayloadSpec(func(key string) string { return args[key] })
+	if err != nil {
+		return err
+	}
+
+	client := p.CurrentS3WorkloadCfg.Client
+	if client == nil {
+		client = S3Client_S3GW
+	}
+	bucketName := args["bn"]
+	objName := argOrDefault(args, "on", "burst")
+
+	CreateBucket(client, bucketName)
+	for i := uint64(0); i < count; i++ {
+		payload, size := payloadSpec.NewReader()
+		start, end, retries, err := SendObject(client, bucketName, objName+"_"+strconv.FormatUint(i, 10), payload)
+		p.recordS3WorkloadEvent(BurstMark(p.CurrentMark), S3OpPutObject, start, end, size, retries, err)
+	}
+	return nil
+}
+
+func hookSetTaint(p *Probe, args map[string]string) error {
+	return K8sCli.SetTaint(args["node"], args["key"], args["val"], v1.TaintEffect(args["effect"]))
+}
+
+func hookUnsetTaint(p *Probe, args map[string]string) error {
+	return K8sCli.UnsetTaint(args["node"], args["key"], args["val"], v1.TaintEffect(args["effect"]))
+}
+
+func hookScale(p *Probe, args map[string]string) error {
+	replicas, err := strconv.ParseInt(args["replicas"], 0, 32)
+	if err != nil {
+		return err
+	}
+	K8sCli.SetReplicasForDeployment(argOrDefault(args, "ns", Cfg.S3GWNamespace),
+		argOrDefault(args, "d_name", Cfg.S3GWDeployment),
+		int32(replicas))
+	return nil
+}
+
+// hookWait waits for ms milliseconds or, when cond is given, until the
+// condition holds: s3-ok (the s3gw endpoint answers) or ready-replicas
+// (the deployment has at least replicas ready replicas).
+func hookWait(p *Probe, args map[string]string) error {
+	if ms, hit := args["ms"]; hit {
+		val, err := strconv.ParseUint(ms, 0, 32)
+		if err != nil {
+			return err
+		}
+		time.Sleep(time.Duration(val) * time.Millisecond)
+	}
+
+	cond, hit := args["cond"]
+	if !hit {
+		return nil
+	}
+
+	timeout, err := strconv.ParseUint(argOrDefault(args, "timeout_ms", "60000"), 0, 32)
+	if err != nil {
+		return err
+	}
+	poll, err := strconv.ParseUint(argOrDefault(args, "poll_ms", "100"), 0, 32)
+	if err != nil {
+		return err
+	}
+
+	var check func() bool
+	switch cond {
+	case "s3-ok":
+		check = func() bool {
+			_, err := S3Client_S3GW.ListBuckets(&s3.ListBucketsInput{})
+			return err == nil
+		}
+	case "ready-replicas":
+		replicas, err := strconv.ParseInt(argOrDefault(args, "replicas", "1"), 0, 32)
+		if err != nil {
+			return err
+		}
+		check = func() bool {
+			ready, err := K8sCli.GetReadyReplicasForDeployment(argOrDefault(args, "ns", Cfg.S3GWNamespace),
+				argOrDefault(args, "d_name", Cfg.S3GWDeployment))
+			return err == nil && ready >= int32(replicas)
+		}
+	default:
+		return errors.New("unknown wait condition: " + cond)
+	}
+
+	deadline := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	for !check() {
+		if time.Now().After(deadline) {
+			return errors.New("timeout waiting for condition: " + cond)
+		}
+		time.Sleep(time.Duration(poll) * time.Millisecond)
+	}
+	return nil
+}
+
+// hookWebhook calls an external url sending the current probe state as JSON.
+func hookWebhook(p *Probe, args map[string]string) error {
+	url, hit := args["url"]
+	if !hit {
+		return errors.New("missing webhook url")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"mark":             p.CurrentMark,
+		"restart_id":       p.CurrentId,
+		"pending_restarts": p.CurrentPendingRestarts,
+		"death_type":       p.CurrentDeathType,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(argOrDefault(args, "method", "POST"), url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	timeout, err := strconv.ParseUint(argOrDefault(args, "timeout_ms", "5000"), 0, 32)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Millisecond}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return errors.New("webhook returned: " + resp.Status)
+	}
+	return nil
+}
+
+func hookSweepStep(p *Probe, args map[string]string) error {
+	p.SweepInterpose()
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHook(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    *Hook
+		wantErr bool
+	}{
+		{"pre-death:fill:bucket=b,obj_base_name=o,obj_count=100,every=2",
+			&Hook{Phase: HookPhasePreDeath, Action: "fill", Every: 2,
+				Args: map[string]string{"bucket": "b", "obj_base_name": "o", "obj_count": "100", "every": "2"}}, false},
+		{"post-fup:wait:ms=500",
+			&Hook{Phase: HookPhasePostFrontendUp, Action: "wait", Args: map[string]string{"ms": "500"}}, false},
+		{"lie-down:scale",
+			&Hook{Phase: HookPhaseLieDown, Action: "scale", Args: map[string]string{}}, false},
+		{"pre-death:webhook:",
+			&Hook{Phase: HookPhasePreDeath, Action: "webhook", Args: map[string]string{}}, false},
+		{"pre-death:webhook:url=http://h/p?a=b",
+			&Hook{Phase: HookPhasePreDeath, Action: "webhook", Args: map[string]string{"url": "http://h/p?a=b"}}, false},
+		{"pre-death:fill:bucket=b;obj_count=100", nil, true},
+		{"pre-death", nil, true},
+		{"mid-death:fill", nil, true},
+		{"pre-death:nope", nil, true},
+		{"pre-death:" + HookActionSweepStep, nil, true},
+		{"pre-death:fill:bucket", nil, true},
+		{"pre-death:fill:every=x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHook(tt.str)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHook(%q) error = %v, wantErr %v", tt.str, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseHook(%q) = %+v, want %+v", tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestHookActionNamesHidesInternalActions(t *testing.T) {
+	for _, name := range HookActionNames() {
+		if _, hit := internalHookActions[name]; hit {
+			t.Errorf("internal hook action %q listed as public", name)
+		}
+	}
+	if _, hit := lookupHookAction(HookActionSweepStep); !hit {
+		t.Errorf("internal hook action %q not found", HookActionSweepStep)
+	}
+}
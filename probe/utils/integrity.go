@@ -0,0 +1,158 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/md5"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	IntegrityMissing  = "missing"
+	IntegrityMismatch = "mismatch"
+)
+
+// IntegrityCheck is the outcome of the read-back of an object acknowledged
+// by the workload; Reason is empty when the content matched.
+type IntegrityCheck struct {
+	Ts     int64  `json:"ts"` //ns
+	Object string `json:"object"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// IntegrityCounts holds the read-back checks done for a mark and how many of them failed.
+type IntegrityCounts struct {
+	Checks   uint
+	Failures uint
+}
+
+// S3WorkloadIntegrity holds the integrity counts per mark.
+type S3WorkloadIntegrity map[string]*IntegrityCounts
+
+func (integrity S3WorkloadIntegrity) Record(mark string, check *IntegrityCheck) {
+	if integrity[mark] == nil {
+		integrity[mark] = &IntegrityCounts{}
+	}
+	integrity[mark].Checks++
+	if check.Reason != "" {
+		integrity[mark].Failures++
+	}
+}
+
+// payloadMD5 returns the MD5 of the payload and rewinds it.
+func payloadMD5(payload io.ReadSeeker) ([md5.Size]byte, error) {
+	var sum [md5.Size]byte
+	hash := md5.New()
+	if _, err := io.Copy(hash, payload); err != nil {
+		return sum, err
+	}
+	copy(sum[:], hash.Sum(nil))
+	_, err := payload.Seek(0, io.SeekStart)
+	return sum, err
+}
+
+// VerifyObject reads back an object and compares its content with the MD5 of
+// the payload acknowledged for it; nil is returned when the read fails for
+// another reason than a missing object, so that the check can be retried.
+func VerifyObject(client *s3.S3, bucketName string, objName string, sum [md5.Size]byte) *IntegrityCheck {
+	out, err := client.GetObject(&s3.GetObjectInput{Bucket: &bucketName, Key: &objName})
+	check := &IntegrityCheck{Ts: time.Now().UnixNano(), Object: objName}
+	if err != nil {
+		if errCode, _ := GetErrorCode(err); errCode == s3.ErrCodeNoSuchKey {
+			check.Reason = IntegrityMissing
+			return check
+		}
+		Logger.Debugf("VerifyObject:%s", err.Error())
+		return nil
+	}
+	defer out.Body.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, out.Body); err != nil {
+		Logger.Debugf("VerifyObject:%s", err.Error())
+		return nil
+	}
+	var readSum [md5.Size]byte
+	copy(readSum[:], hash.Sum(nil))
+	if readSum != sum {
+		check.Reason = IntegrityMismatch
+	}
+	return check
+}
+
+type pendingObject struct {
+	name string
+	sum  [md5.Size]byte
+}
+
+// IntegrityVerifier names the objects the workload puts with verify=1, one
+// key per put so that an object is never overwritten before being verified,
+// and keeps their checksums until their read-back is conclusive.
+type IntegrityVerifier struct {
+	client  *s3.S3
+	bucket  string
+	objName string
+	seq     int
+	pending []pendingObject
+}
+
+func NewIntegrityVerifier(client *s3.S3, bucketName string, objName string) *IntegrityVerifier {
+	return &IntegrityVerifier{client: client, bucket: bucketName, objName: objName}
+}
+
+// NextName returns the key of the next object to put.
+func (v *IntegrityVerifier) NextName() string {
+	v.seq++
+	return v.objName + "_" + strconv.Itoa(v.seq)
+}
+
+// Acked records the checksum of an object whose put has been acknowledged.
+func (v *IntegrityVerifier) Acked(objName string, sum [md5.Size]byte) {
+	v.pending = append(v.pending, pendingObject{name: objName, sum: sum})
+}
+
+func (v *IntegrityVerifier) Pending() int {
+	return len(v.pending)
+}
+
+// Verify reads back the pending objects, oldest first, and returns the
+// conclusive checks; it stops at the first read failing for another reason
+// than a missing object, the gateway being likely unavailable, and keeps it
+// pending. The objects found intact are deleted.
+func (v *IntegrityVerifier) Verify() []*IntegrityCheck {
+	var checks []*IntegrityCheck
+	for len(v.pending) > 0 {
+		obj := v.pending[0]
+		check := VerifyObject(v.client, v.bucket, obj.name, obj.sum)
+		if check == nil {
+			break
+		}
+		checks = append(checks, check)
+		v.pending = v.pending[1:]
+		if check.Reason == "" {
+			EraseObject(v.client, v.bucket, obj.name)
+		}
+	}
+	return checks
+}
+
+func (series *SeriesS3WorkloadEntry) setIntegrity(counts *IntegrityCounts) {
+	if counts == nil {
+		return
+	}
+	series.IntegrityChecks = counts.Checks
+	series.IntegrityFailures = counts.Failures
+}
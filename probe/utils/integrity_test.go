@@ -0,0 +1,92 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/md5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+func TestIntegrityVerifierRetriesTransientReadFailure(t *testing.T) {
+	if Logger == nil {
+		Logger = logrus.New()
+	}
+
+	const content = "payload"
+	var gets, deletes int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			gets++
+			if gets == 1 {
+				//gateway still restarting
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(content))
+		case http.MethodDelete:
+			deletes++
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	sess, err := session.NewSession(&aws.Config{Endpoint: aws.String(srv.URL),
+		Region:           aws.String("US"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:       aws.Int(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewIntegrityVerifier(s3.New(sess), "b", "o")
+	name := verifier.NextName()
+	if name != "o_1" {
+		t.Fatalf("NextName() = %s, want o_1", name)
+	}
+	sum, err := payloadMD5(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier.Acked(name, sum)
+
+	if checks := verifier.Verify(); len(checks) != 0 || verifier.Pending() != 1 {
+		t.Fatalf("after a transient failure: %d checks, %d pending, want 0 checks, 1 pending", len(checks), verifier.Pending())
+	}
+
+	checks := verifier.Verify()
+	if len(checks) != 1 || verifier.Pending() != 0 {
+		t.Fatalf("after a successful read: %d checks, %d pending, want 1 check, 0 pending", len(checks), verifier.Pending())
+	}
+	if checks[0].Object != name || checks[0].Reason != "" {
+		t.Errorf("check = %+v, want an intact %s", checks[0], name)
+	}
+	if deletes != 1 {
+		t.Errorf("deletes = %d, want the verified object deleted once", deletes)
+	}
+
+	var mismatch [md5.Size]byte
+	verifier.Acked(verifier.NextName(), mismatch)
+	if checks := verifier.Verify(); len(checks) != 1 || checks[0].Reason != IntegrityMismatch {
+		t.Errorf("checks = %+v, want one %s", checks, IntegrityMismatch)
+	}
+}
@@ -13,6 +13,7 @@ package utils
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -69,6 +70,53 @@ func (k8s *K8sClient) SetReplicasForDeployment(ns string, dName string, replicas
 	}
 }
 
+func (k8s *K8sClient) GetReadyReplicasForDeployment(ns string, dName string) (int32, error) {
+	ClientSet, err := kubernetes.NewForConfig(k8s.ClusterConfig)
+	if err != nil {
+		Logger.Errorf("NewForConfig: %s", err.Error())
+		return 0, err
+	}
+
+	if deployment, err := ClientSet.AppsV1().Deployments(ns).Get(context.TODO(), dName, metav1.GetOptions{}); err == nil {
+		return deployment.Status.ReadyReplicas, nil
+	} else {
+		return 0, err
+	}
+}
+
+// GetEvents returns the events of the namespace that occurred in [from, to], timestamps in ns.
+func (k8s *K8sClient) GetEvents(ns string, from int64, to int64) ([]K8sEventEntry, error) {
+	ClientSet, err := kubernetes.NewForConfig(k8s.ClusterConfig)
+	if err != nil {
+		Logger.Errorf("NewForConfig: %s", err.Error())
+		return nil, err
+	}
+
+	list, err := ClientSet.CoreV1().Events(ns).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []K8sEventEntry
+	for _, evt := range list.Items {
+		ts := evt.EventTime.UnixNano()
+		if evt.EventTime.IsZero() {
+			ts = evt.LastTimestamp.UnixNano()
+		}
+		if ts < from || ts > to {
+			continue
+		}
+		events = append(events, K8sEventEntry{Ts: ts,
+			Namespace: ns,
+			Type:      evt.Type,
+			Reason:    evt.Reason,
+			Object:    evt.InvolvedObject.Kind + "/" + evt.InvolvedObject.Name,
+			Message:   evt.Message})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Ts < events[j].Ts })
+	return events, nil
+}
+
 func (k8s *K8sClient) GetNodeNameList() (*[]string, error) {
 	ClientSet, err := kubernetes.NewForConfig(k8s.ClusterConfig)
 	if err != nil {
@@ -0,0 +1,155 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	LiveEventPhase           = "phase"
+	LiveEventDeath           = "death"
+	LiveEventStart           = "start"
+	LiveEventRestart         = "restart"
+	LiveEventS3WLErrorBurst  = "s3wl_error_burst"
+	liveFeedSubscriberBuffer = 256
+)
+
+// LiveEvent is a structured event streamed to the live feed subscribers.
+type LiveEvent struct {
+	Type string      `json:"type"`
+	Ts   int64       `json:"ts"` //ns
+	Mark string      `json:"mark"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+type LivePhase struct {
+	State           string `json:"state"`
+	PendingRestarts uint   `json:"pending_restarts"`
+}
+
+type LiveRestart struct {
+	Id                int     `json:"id"`
+	DeathType         string  `json:"death_type"`
+	Node              string  `json:"node,omitempty"`
+	ToMainMs          float64 `json:"to_main_ms"`
+	ToFrontendUpMs    float64 `json:"to_frontend_up_ms"`
+	MissingMain       bool    `json:"missing_main"`
+	MissingFrontendUp bool    `json:"missing_frontend_up"`
+	PendingRestarts   uint    `json:"pending_restarts"`
+}
+
+// S3WorkloadErrorBurst is a run of consecutive failed S3 workload requests.
+type S3WorkloadErrorBurst struct {
+	Status     string         `json:"status"` //begin, end
+	Op         string         `json:"op"`
+	StartTs    int64          `json:"start_ts"`
+	EndTs      int64          `json:"end_ts,omitempty"`
+	Count      int            `json:"count"`
+	Categories map[string]int `json:"categories"`
+}
+
+type liveFeed struct {
+	mtx         sync.Mutex
+	subscribers map[chan *LiveEvent]struct{}
+}
+
+var LiveFeed = liveFeed{subscribers: make(map[chan *LiveEvent]struct{})}
+
+// Subscribe returns the channel of the live events and the function
+// to call when done with it.
+func (f *liveFeed) Subscribe() (<-chan *LiveEvent, func()) {
+	ch := make(chan *LiveEvent, liveFeedSubscriberBuffer)
+	f.mtx.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mtx.Unlock()
+
+	return ch, func() {
+		f.mtx.Lock()
+		delete(f.subscribers, ch)
+		f.mtx.Unlock()
+	}
+}
+
+// Publish sends the event to every subscriber;
+// a slow subscriber with a full buffer misses the event.
+func (f *liveFeed) Publish(evtType string, mark string, data interface{}) {
+	evt := &LiveEvent{Type: evtType, Ts: time.Now().UnixNano(), Mark: mark, Data: data}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- evt:
+		default:
+			Logger.Warnf("live feed subscriber too slow, %s event dropped", evtType)
+		}
+	}
+}
+
+func (p *Probe) publishRestart(evt *RestartEvent, missingMain bool, missingFrontendUp bool) {
+	restart := LiveRestart{Id: evt.Id,
+		DeathType:         evt.How,
+		Node:              evt.Node,
+		MissingMain:       missingMain,
+		MissingFrontendUp: missingFrontendUp,
+		PendingRestarts:   p.CurrentPendingRestarts}
+	if restart.DeathType == "" {
+		restart.DeathType = evt.Death.Type
+	}
+	if !missingMain {
+		restart.ToMainMs = float64(evt.StartMain.Ts-evt.Death.Ts) / MilliS
+	}
+	if !missingFrontendUp {
+		restart.ToFrontendUpMs = float64(evt.StartFrontendUp.Ts-evt.Death.Ts) / MilliS
+	}
+	LiveFeed.Publish(LiveEventRestart, p.CurrentMark, &restart)
+}
+
+// trackS3WorkloadErrorBurst publishes the begin of a burst at its first error
+// and its end, with the count of errors, at the first successful request.
+// To be called holding S3WorkloadMtx.
+func (p *Probe) trackS3WorkloadErrorBurst(op string, evt *S3WorkloadEvent) {
+	burst := p.CurrentS3WorkloadErrorBurst
+	if evt.ErrCat != ErrCatNone {
+		if burst == nil {
+			burst = &S3WorkloadErrorBurst{Op: op, StartTs: evt.StartTs, Categories: make(map[string]int)}
+			p.CurrentS3WorkloadErrorBurst = burst
+			LiveFeed.Publish(LiveEventS3WLErrorBurst, p.CurrentMark, &S3WorkloadErrorBurst{Status: "begin",
+				Op:         op,
+				StartTs:    evt.StartTs,
+				Count:      1,
+				Categories: map[string]int{evt.ErrCat: 1}})
+		}
+		burst.Count++
+		burst.Categories[evt.ErrCat]++
+	} else if burst != nil {
+		burst.EndTs = evt.StartTs
+		p.endS3WorkloadErrorBurst()
+	}
+}
+
+// endS3WorkloadErrorBurst publishes the end of the burst in progress, if any.
+// To be called holding S3WorkloadMtx.
+func (p *Probe) endS3WorkloadErrorBurst() {
+	burst := p.CurrentS3WorkloadErrorBurst
+	if burst == nil {
+		return
+	}
+	if burst.EndTs == 0 {
+		burst.EndTs = time.Now().UnixNano()
+	}
+	burst.Status = "end"
+	p.CurrentS3WorkloadErrorBurst = nil
+	LiveFeed.Publish(LiveEventS3WLErrorBurst, p.CurrentMark, burst)
+}
@@ -0,0 +1,117 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	ProbeStateIdle       = "idle"       //no campaign in progress
+	ProbeStateRunning    = "running"    //campaign in progress, radosgw up
+	ProbeStateRestarting = "restarting" //death received, waiting for the radosgw to be up
+	ProbeStateWrapUp     = "wrap_up"    //computing and saving the results
+)
+
+var probeStates = []string{ProbeStateIdle, ProbeStateRunning, ProbeStateRestarting, ProbeStateWrapUp}
+
+// restart durations in seconds, from 100ms to ~4 minutes
+var restartDurationBuckets = prometheus.ExponentialBuckets(0.1, 1.5, 20)
+
+var (
+	metricRestartToMain = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "s3gw_probe_restart_to_main_seconds",
+		Help:    "Duration from the death to the radosgw main.",
+		Buckets: restartDurationBuckets,
+	}, []string{"mark", "death_type"})
+
+	metricRestartToFrontendUp = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "s3gw_probe_restart_to_frontend_up_seconds",
+		Help:    "Duration from the death to the radosgw frontend up.",
+		Buckets: restartDurationBuckets,
+	}, []string{"mark", "death_type"})
+
+	metricRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "s3gw_probe_restarts_total",
+		Help: "Restarts collected.",
+	}, []string{"mark", "death_type"})
+
+	metricFailedRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "s3gw_probe_failed_restarts_total",
+		Help: "Restarts collected without the main or the frontend-up start event.",
+	}, []string{"mark", "death_type", "missing"})
+
+	metricS3WorkloadRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "s3gw_probe_s3_workload_requests_total",
+		Help: "S3 workload requests.",
+	}, []string{"mark", "op"})
+
+	metricS3WorkloadErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "s3gw_probe_s3_workload_errors_total",
+		Help: "S3 workload errors by category.",
+	}, []string{"mark", "op", "category"})
+
+	metricProbeState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "s3gw_probe_state",
+		Help: "Current probe state, 1 for the current one.",
+	}, []string{"state"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "s3gw_probe_pending_restarts",
+		Help: "Restarts still to be performed by the current campaign.",
+	}, func() float64 { return float64(Prb.CurrentPendingRestarts) })
+)
+
+func init() {
+	SetProbeState(ProbeStateIdle)
+}
+
+func SetProbeState(state string) {
+	for _, it := range probeStates {
+		if it == state {
+			metricProbeState.WithLabelValues(it).Set(1)
+		} else {
+			metricProbeState.WithLabelValues(it).Set(0)
+		}
+	}
+	LiveFeed.Publish(LiveEventPhase, Prb.CurrentMark, &LivePhase{State: state, PendingRestarts: Prb.CurrentPendingRestarts})
+}
+
+func observeRestart(mark string, evt *RestartEvent, missingMain bool, missingFrontendUp bool) {
+	deathType := evt.How
+	if deathType == "" {
+		deathType = evt.Death.Type
+	}
+
+	metricRestarts.WithLabelValues(mark, deathType).Inc()
+
+	if missingMain {
+		metricFailedRestarts.WithLabelValues(mark, deathType, "main").Inc()
+	}
+	if missingFrontendUp {
+		metricFailedRestarts.WithLabelValues(mark, deathType, "frontend-up").Inc()
+	}
+	if missingMain || missingFrontendUp {
+		return
+	}
+
+	metricRestartToMain.WithLabelValues(mark, deathType).Observe(float64(evt.StartMain.Ts-evt.Death.Ts) / Sec)
+	metricRestartToFrontendUp.WithLabelValues(mark, deathType).Observe(float64(evt.StartFrontendUp.Ts-evt.Death.Ts) / Sec)
+}
+
+func observeS3WorkloadEvent(mark string, op string, evt *S3WorkloadEvent) {
+	metricS3WorkloadRequests.WithLabelValues(mark, op).Inc()
+	if evt.ErrCat != ErrCatNone {
+		metricS3WorkloadErrors.WithLabelValues(mark, op, evt.ErrCat).Inc()
+	}
+}
@@ -77,3 +77,12 @@ func SignHTTPRequestV4(request *http.Request, creds *Credentials) error {
 	}
 	return nil
 }
+
+func containsString(list []string, val string) bool {
+	for _, it := range list {
+		if it == val {
+			return true
+		}
+	}
+	return false
+}
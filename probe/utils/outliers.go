@@ -0,0 +1,174 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/montanaflynn/stats"
+)
+
+const (
+	OutlierMethodNone = "none"
+	OutlierMethodIQR  = "iqr" //outside [Q1 - k*IQR, Q3 + k*IQR]
+	OutlierMethodMAD  = "mad" //modified z-score above k
+)
+
+// the Kubernetes events of an outlier are searched in its restart window
+// widened by this margin.
+const outlierEventsMarginMSecs = 5000
+
+var outlierDefaultK = map[string]float64{OutlierMethodIQR: 1.5, OutlierMethodMAD: 3.5}
+
+// OutlierConfig configures the detection of the outlier restarts.
+type OutlierConfig struct {
+	Method          string   `json:"method"`
+	K               float64  `json:"k"`
+	EventNamespaces []string `json:"event_namespaces"`
+}
+
+func DefaultOutlierConfig() OutlierConfig {
+	return OutlierConfig{Method: OutlierMethodIQR,
+		K:               outlierDefaultK[OutlierMethodIQR],
+		EventNamespaces: []string{Cfg.S3GWNamespace}}
+}
+
+// ParseOutlierConfig parses the method, the k factor and the comma separated
+// namespaces where to look for events; empty values are defaulted.
+func ParseOutlierConfig(method string, k string, namespaces string) (OutlierConfig, error) {
+	cfg := DefaultOutlierConfig()
+	if method != "" {
+		if _, hit := outlierDefaultK[method]; !hit && method != OutlierMethodNone {
+			return cfg, errors.New("unknown outlier method: " + method)
+		}
+		cfg.Method = method
+		cfg.K = outlierDefaultK[method]
+	}
+	if k != "" {
+		val, err := strconv.ParseFloat(k, 64)
+		if err != nil {
+			return cfg, err
+		}
+		if val <= 0 {
+			return cfg, errors.New("outlier k must be positive: " + k)
+		}
+		cfg.K = val
+	}
+	if namespaces != "" {
+		cfg.EventNamespaces = strings.Split(namespaces, ",")
+	}
+	return cfg, nil
+}
+
+// OutlierBounds returns the range outside of which a sample is an outlier.
+func OutlierBounds(data []float64, method string, k float64) (float64, float64, error) {
+	switch method {
+	case OutlierMethodIQR:
+		quartiles, err := stats.Quartile(data)
+		if err != nil {
+			return 0, 0, err
+		}
+		iqr := quartiles.Q3 - quartiles.Q1
+		return quartiles.Q1 - k*iqr, quartiles.Q3 + k*iqr, nil
+	case OutlierMethodMAD:
+		median, err := stats.Median(data)
+		if err != nil {
+			return 0, 0, err
+		}
+		mad, err := stats.MedianAbsoluteDeviation(data)
+		if err != nil {
+			return 0, 0, err
+		}
+		if mad == 0 {
+			return math.Inf(-1), math.Inf(1), nil
+		}
+		//modified z-score: 0.6745 * (x - median) / MAD
+		return median - k*mad/0.6745, median + k*mad/0.6745, nil
+	}
+	return math.Inf(-1), math.Inf(1), nil
+}
+
+func outlierMask(data []float64, cfg *OutlierConfig) []bool {
+	mask := make([]bool, len(data))
+	low, high, err := OutlierBounds(data, cfg.Method, cfg.K)
+	if err != nil {
+		return mask
+	}
+	for i, val := range data {
+		mask[i] = val < low || val > high
+	}
+	return mask
+}
+
+// captureRestartEvents returns the Kubernetes events of the namespaces around
+// a restart; it is called when the restart is collected, as Kubernetes drops
+// the events after a while, so the events up to that moment are returned.
+func captureRestartEvents(evt *RestartEvent, namespaces []string) []K8sEventEntry {
+	from := evt.Death.Ts - outlierEventsMarginMSecs*MilliS
+	to := restartWindowEnd(evt) + outlierEventsMarginMSecs*MilliS
+
+	var events []K8sEventEntry
+	if K8sCli.ClusterConfig == nil {
+		return events
+	}
+	for _, ns := range namespaces {
+		if nsEvents, err := K8sCli.GetEvents(ns, from, to); err == nil {
+			events = append(events, nsEvents...)
+		} else {
+			Logger.Errorf("GetEvents: %s", err.Error())
+		}
+	}
+	return events
+}
+
+// ComputeRestartOutliers flags the restarts whose duration to main or to
+// frontend-up is an outlier and fills the series with them and with the stats
+// computed without them.
+func ComputeRestartOutliers(series *SeriesRestartEntry,
+	evtSeries []RestartEntry,
+	restartEvents []RestartEvent,
+	evtSeriesMainData []float64,
+	evtSeriesFrontedUpData []float64,
+	evtSeriesFUpMainDelta []float64,
+	percentiles []float64,
+	cfg *OutlierConfig) {
+
+	var mainNO, fUpNO, deltaNO []float64
+
+	if cfg.Method != "" && cfg.Method != OutlierMethodNone {
+		mainMask := outlierMask(evtSeriesMainData, cfg)
+		fUpMask := outlierMask(evtSeriesFrontedUpData, cfg)
+
+		for i := range evtSeries {
+			if mainMask[i] || fUpMask[i] {
+				evtSeries[i].Outlier = true
+				series.Outliers = append(series.Outliers, RestartOutlierEntry{Id: evtSeries[i].Id,
+					RestartDurationToMain:       evtSeries[i].RestartDurationToMain,
+					RestartDurationToFrontendUp: evtSeries[i].RestartDurationToFrontendUp,
+					K8sEvents:                   restartEvents[i].K8sEvents})
+				continue
+			}
+			mainNO = append(mainNO, evtSeriesMainData[i])
+			fUpNO = append(fUpNO, evtSeriesFrontedUpData[i])
+			deltaNO = append(deltaNO, evtSeriesFUpMainDelta[i])
+		}
+	} else {
+		mainNO, fUpNO, deltaNO = evtSeriesMainData, evtSeriesFrontedUpData, evtSeriesFUpMainDelta
+	}
+
+	series.ToMainNoOutliers = ComputeDistSummary(mainNO, percentiles)
+	series.ToFrontendUpNoOutliers = ComputeDistSummary(fUpNO, percentiles)
+	series.FUpMainDeltaNoOutliers = ComputeDistSummary(deltaNO, percentiles)
+}
@@ -0,0 +1,280 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PayloadDistFixed     = "fixed"
+	PayloadDistUniform   = "uniform"
+	PayloadDistLogNormal = "lognormal"
+)
+
+const (
+	PayloadContentRandom       = "random"
+	PayloadContentCompressible = "compressible"
+	PayloadContentZero         = "zero"
+)
+
+const payloadBlockSize = 64 * 1024
+
+var compressiblePattern = []byte("s3gw-probe compressible payload 0123456789 ")
+
+// PayloadSpec describes how the payload of the objects sent
+// by a workload is generated.
+type PayloadSpec struct {
+	Literal string //when set, the payload is this literal string
+	Dist    string
+	Size    int64 //fixed size or median size for lognormal, bytes
+	MinSize int64 //bytes
+	MaxSize int64 //bytes
+	Sigma   float64
+	Content string
+	Seed    int64
+
+	mtx sync.Mutex
+	rnd *rand.Rand
+}
+
+// ParseSize parses a size in bytes with an optional k, M, G suffix (base 1024).
+func ParseSize(str string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(str, "k"), strings.HasSuffix(str, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(str, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(str, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		str = str[:len(str)-1]
+	}
+	val, err := strconv.ParseInt(str, 0, 64)
+	if err != nil {
+		return 0, err
+	}
+	if val < 0 {
+		return 0, errors.New("negative size")
+	}
+	return val * mult, nil
+}
+
+// ParsePayloadSpec builds a PayloadSpec reading its parameters with getPar:
+//   - pl: literal payload, used when no pl-size is given
+//   - pl-dist: fixed, uniform, lognormal (default fixed)
+//   - pl-size: size for fixed, median size for lognormal
+//   - pl-min, pl-max: bounds for uniform, clamps for lognormal
+//   - pl-sigma: sigma for lognormal (default 1)
+//   - pl-content: random, compressible, zero (default random)
+//   - pl-seed: seed of the generator (default current time)
+func ParsePayloadSpec(getPar func(string) string) (*PayloadSpec, error) {
+	spec := &PayloadSpec{Dist: PayloadDistFixed, Content: PayloadContentRandom, Sigma: 1}
+
+	if getPar("pl-size") == "" && getPar("pl-dist") == "" {
+		spec.Literal = getPar("pl")
+		return spec, nil
+	}
+
+	var err error
+	if par := getPar("pl-dist"); par != "" {
+		spec.Dist = par
+	}
+	if par := getPar("pl-size"); par != "" {
+		if spec.Size, err = ParseSize(par); err != nil {
+			return nil, err
+		}
+	}
+	if par := getPar("pl-min"); par != "" {
+		if spec.MinSize, err = ParseSize(par); err != nil {
+			return nil, err
+		}
+	}
+	if par := getPar("pl-max"); par != "" {
+		if spec.MaxSize, err = ParseSize(par); err != nil {
+			return nil, err
+		}
+	}
+	if par := getPar("pl-sigma"); par != "" {
+		if spec.Sigma, err = strconv.ParseFloat(par, 64); err != nil {
+			return nil, err
+		}
+	}
+	if par := getPar("pl-content"); par != "" {
+		spec.Content = par
+	}
+	if par := getPar("pl-seed"); par != "" {
+		if spec.Seed, err = strconv.ParseInt(par, 0, 64); err != nil {
+			return nil, err
+		}
+	} else {
+		spec.Seed = time.Now().UnixNano()
+	}
+
+	switch spec.Dist {
+	case PayloadDistFixed, PayloadDistLogNormal:
+		if spec.Size == 0 {
+			return nil, errors.New("missing or zero pl-size for pl-dist: " + spec.Dist)
+		}
+	case PayloadDistUniform:
+		if spec.MaxSize == 0 {
+			return nil, errors.New("missing or zero pl-max for pl-dist: " + spec.Dist)
+		}
+		if spec.MaxSize < spec.MinSize {
+			return nil, errors.New("pl-max lower than pl-min")
+		}
+	default:
+		return nil, errors.New("unknown pl-dist: " + spec.Dist)
+	}
+
+	switch spec.Content {
+	case PayloadContentRandom, PayloadContentCompressible, PayloadContentZero:
+	default:
+		return nil, errors.New("unknown pl-content: " + spec.Content)
+	}
+
+	spec.Reseed()
+	return spec, nil
+}
+
+// Reseed restarts the generator from the seed, e.g. for a spec restored from its JSON form.
+func (spec *PayloadSpec) Reseed() {
+	spec.mtx.Lock()
+	defer spec.mtx.Unlock()
+	spec.rnd = rand.New(rand.NewSource(spec.Seed))
+}
+
+// NextSize draws the size of the next object from the distribution.
+func (spec *PayloadSpec) NextSize() int64 {
+	if spec.Literal != "" || spec.rnd == nil {
+		return int64(len(spec.Literal))
+	}
+
+	spec.mtx.Lock()
+	defer spec.mtx.Unlock()
+
+	switch spec.Dist {
+	case PayloadDistUniform:
+		return spec.MinSize + spec.rnd.Int63n(spec.MaxSize-spec.MinSize+1)
+	case PayloadDistLogNormal:
+		size := int64(float64(spec.Size) * math.Exp(spec.Sigma*spec.rnd.NormFloat64()))
+		if spec.MinSize > 0 && size < spec.MinSize {
+			size = spec.MinSize
+		}
+		if spec.MaxSize > 0 && size > spec.MaxSize {
+			size = spec.MaxSize
+		}
+		return size
+	default:
+		return spec.Size
+	}
+}
+
+// NewReader returns a reader over the payload of the next object and its size.
+// The content is generated on the fly, so the object is never held in memory.
+func (spec *PayloadSpec) NewReader() (io.ReadSeeker, int64) {
+	if spec.Literal != "" || spec.rnd == nil {
+		return strings.NewReader(spec.Literal), int64(len(spec.Literal))
+	}
+
+	size := spec.NextSize()
+	spec.mtx.Lock()
+	seed := spec.rnd.Int63()
+	spec.mtx.Unlock()
+
+	return &PayloadReader{size: size, seed: seed, content: spec.Content, blockIdx: -1}, size
+}
+
+// PayloadReader is a seekable reader generating a payload block by block.
+// The content of each block depends only on the seed and the block index,
+// so that the payload can be read again after a seek (e.g. for signing).
+type PayloadReader struct {
+	size     int64
+	offset   int64
+	seed     int64
+	content  string
+	block    []byte
+	blockIdx int64
+}
+
+func (r *PayloadReader) fillBlock(idx int64) {
+	if r.block == nil {
+		r.block = make([]byte, payloadBlockSize)
+	}
+	r.blockIdx = idx
+
+	switch r.content {
+	case PayloadContentZero:
+		for i := range r.block {
+			r.block[i] = 0
+		}
+	case PayloadContentCompressible:
+		start := idx * payloadBlockSize
+		for i := range r.block {
+			r.block[i] = compressiblePattern[(start+int64(i))%int64(len(compressiblePattern))]
+		}
+	default:
+		rand.New(rand.NewSource(r.seed ^ (idx * 0x5DEECE66D))).Read(r.block)
+	}
+}
+
+// Read implements io.Reader.
+func (r *PayloadReader) Read(buf []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	read := 0
+	for read < len(buf) && r.offset < r.size {
+		idx := r.offset / payloadBlockSize
+		if idx != r.blockIdx {
+			r.fillBlock(idx)
+		}
+		blockOffset := r.offset % payloadBlockSize
+		n := copy(buf[read:], r.block[blockOffset:])
+		if remaining := r.size - r.offset; int64(n) > remaining {
+			n = int(remaining)
+		}
+		read += n
+		r.offset += int64(n)
+	}
+	return read, nil
+}
+
+// Seek implements io.Seeker.
+func (r *PayloadReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.offset = abs
+	return abs, nil
+}
@@ -0,0 +1,129 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+)
+
+const (
+	PlotFormatSVG = "svg"
+	PlotFormatPNG = "png"
+	PlotFormatPDF = "pdf"
+
+	plotMaxSize = 200 //cm
+	plotMaxBins = 1000
+)
+
+var plotFormats = []string{PlotFormatSVG, PlotFormatPNG, PlotFormatPDF}
+
+// PlotConfig configures the rendering of the plots.
+type PlotConfig struct {
+	Formats     []string `json:"formats"`   //every plot is saved in each format
+	Width       float64  `json:"width"`     //cm
+	Height      float64  `json:"height"`    //cm
+	CDFLogScale bool     `json:"cdf_log"`   //log scale on the x axis of the CDF plots
+	HistBins    int      `json:"hist_bins"` //bins of the histogram plots
+}
+
+func DefaultPlotConfig() PlotConfig {
+	return PlotConfig{Formats: []string{PlotFormatSVG}, Width: 30, Height: 20, CDFLogScale: false, HistBins: 30}
+}
+
+func parsePlotSize(par string, str string) (float64, error) {
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, err
+	}
+	if val <= 0 || val > plotMaxSize {
+		return 0, errors.New(par + " out of range (0, " + strconv.Itoa(plotMaxSize) + "]: " + str)
+	}
+	return val, nil
+}
+
+// ParsePlotConfig parses the plot_format (comma separated svg, png, pdf),
+// plot_width, plot_height (cm), cdf_log and hist_bins parameters;
+// empty values are defaulted.
+func ParsePlotConfig(getPar func(string) string) (PlotConfig, error) {
+	cfg := DefaultPlotConfig()
+	if formats := getPar("plot_format"); formats != "" {
+		cfg.Formats = nil
+		for _, format := range strings.Split(formats, ",") {
+			format = strings.ToLower(strings.TrimSpace(format))
+			if !containsString(plotFormats, format) {
+				return cfg, errors.New("unknown plot format: " + format)
+			}
+			if !containsString(cfg.Formats, format) {
+				cfg.Formats = append(cfg.Formats, format)
+			}
+		}
+	}
+	if width := getPar("plot_width"); width != "" {
+		val, err := parsePlotSize("plot_width", width)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Width = val
+	}
+	if height := getPar("plot_height"); height != "" {
+		val, err := parsePlotSize("plot_height", height)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Height = val
+	}
+	if cdfLog := getPar("cdf_log"); cdfLog != "" {
+		val, err := strconv.ParseBool(cdfLog)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.CDFLogScale = val
+	}
+	if histBins := getPar("hist_bins"); histBins != "" {
+		val, err := strconv.Atoi(histBins)
+		if err != nil {
+			return cfg, err
+		}
+		if val <= 0 || val > plotMaxBins {
+			return cfg, errors.New("hist_bins out of range (0, " + strconv.Itoa(plotMaxBins) + "]: " + histBins)
+		}
+		cfg.HistBins = val
+	}
+	return cfg, nil
+}
+
+// savePlot saves the plot as fBase.<format> for every configured format
+// and returns the names of the saved files.
+func savePlot(plt *plot.Plot, fBase string, cfg PlotConfig) ([]string, error) {
+	formats, width, height := cfg.Formats, cfg.Width, cfg.Height
+	if len(formats) == 0 {
+		formats = DefaultPlotConfig().Formats
+	}
+	if width <= 0 || height <= 0 {
+		width, height = DefaultPlotConfig().Width, DefaultPlotConfig().Height
+	}
+
+	fNames := []string{}
+	for _, format := range formats {
+		fName := fBase + "." + format
+		if err := plt.Save(vg.Length(width)*vg.Centimeter, vg.Length(height)*vg.Centimeter, fName); err != nil {
+			return fNames, err
+		}
+		fNames = append(fNames, fName)
+	}
+	return fNames, nil
+}
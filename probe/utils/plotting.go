@@ -13,22 +13,24 @@ package utils
 
 import (
 	"errors"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/montanaflynn/stats"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/plotutil"
-	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
 )
 
-func (p *Probe) GenerateRestartRawDataPlot(timeUnit string, genTS string) (string, error) {
+func (p *Probe) GenerateRestartRawDataPlot(mark string, timeUnit string, genTS string, plotCfg PlotConfig) ([]string, error) {
 
-	if restartEvents, hit := p.CollectedRestartRelatedData[p.CurrentMark]; hit {
+	if restartEvents, hit := p.CollectedRestartRelatedData[mark]; hit {
 		plt := plot.New()
 		plt.Add(plotter.NewGrid())
 
-		plt.Title.Text = "Raw Data Measures: " + p.CurrentMark
+		plt.Title.Text = "Raw Data Measures: " + mark
 		plt.X.Label.Text = "Restart ID"
 		plt.Y.Label.Text = "Duration: " + timeUnit
 
@@ -49,7 +51,7 @@ func (p *Probe) GenerateRestartRawDataPlot(timeUnit string, genTS string) (strin
 			lpLineMain, lpPointsMain, err := plotter.NewLinePoints(mainPts)
 			if err != nil {
 				Logger.Error("GeneratePlot-to-main: NewLinePoints", err.Error())
-				return "", err
+				return nil, err
 			}
 			lpLineMain.Color = plotutil.Color(0)
 			lpPointsMain.Shape = draw.PyramidGlyph{}
@@ -59,7 +61,7 @@ func (p *Probe) GenerateRestartRawDataPlot(timeUnit string, genTS string) (strin
 			plt.Legend.Add("to-main", lpLineMain, lpPointsMain)
 		}
 
-		//fronted-up
+		//frontend-up
 
 		{
 			frontendUpPts := make(plotter.XYs, len(restartEvents))
@@ -70,15 +72,15 @@ func (p *Probe) GenerateRestartRawDataPlot(timeUnit string, genTS string) (strin
 
 			lpLineFUp, lpPointsFUp, err := plotter.NewLinePoints(frontendUpPts)
 			if err != nil {
-				Logger.Error("GeneratePlot-to-fronted-up: NewLinePoints", err.Error())
-				return "", err
+				Logger.Error("GeneratePlot-to-frontend-up: NewLinePoints", err.Error())
+				return nil, err
 			}
 			lpLineFUp.Color = plotutil.Color(1)
 			lpPointsFUp.Shape = draw.PyramidGlyph{}
 			lpPointsFUp.Color = plotutil.Color(1)
 
 			plt.Add(lpLineFUp, lpPointsFUp)
-			plt.Legend.Add("to-fronted-up", lpLineFUp, lpPointsFUp)
+			plt.Legend.Add("to-frontend-up", lpLineFUp, lpPointsFUp)
 		}
 
 		//delta
@@ -92,48 +94,84 @@ func (p *Probe) GenerateRestartRawDataPlot(timeUnit string, genTS string) (strin
 
 			lpLineFUpD, lpPointsFUpD, err := plotter.NewLinePoints(fUpMainDeltaPts)
 			if err != nil {
-				Logger.Error("GeneratePlot-fronted-up-main-delta: NewLinePoints", err.Error())
-				return "", err
+				Logger.Error("GeneratePlot-frontend-up-main-delta: NewLinePoints", err.Error())
+				return nil, err
 			}
 			lpLineFUpD.Color = plotutil.Color(2)
 			lpPointsFUpD.Shape = draw.PyramidGlyph{}
 			lpPointsFUpD.Color = plotutil.Color(2)
 
 			plt.Add(lpLineFUpD, lpPointsFUpD)
-			plt.Legend.Add("fronted-up-main-delta", lpLineFUpD, lpPointsFUpD)
+			plt.Legend.Add("frontend-up-main-delta", lpLineFUpD, lpPointsFUpD)
 		}
 
-		fName := genTS + "_" + p.CurrentMark + "_raw" + ".svg"
+		fBase := genTS + "_" + mark + "_raw"
 
-		if err := plt.Save(30*vg.Centimeter, 20*vg.Centimeter, fName); err != nil {
-			Logger.Error("GeneratePlot: Saving plot:", fName)
-			return "", err
+		fNames, err := savePlot(plt, fBase, plotCfg)
+		if err != nil {
+			Logger.Error("GeneratePlot: Saving plot:", fBase)
 		}
-
-		return fName, nil
+		return fNames, err
 
 	} else {
-		Logger.Error("GeneratePlot: no series with mark:", p.CurrentMark)
-		return "", errors.New("no series with mark")
+		Logger.Error("GeneratePlot: no series with mark:", mark)
+		return nil, errors.New("no series with mark")
 	}
 }
 
-func (p *Probe) GenerateRestartPercentilesPlot(timeUnit string, genTS string) (string, string, string, error) {
-	if restartEvents, hit := p.CollectedRestartRelatedData[p.CurrentMark]; hit {
+type percentilesErrors struct {
+	plotter.XYs
+	plotter.YErrors
+}
+
+// newPercentilesErrorBars returns the error bars of the bootstrap confidence
+// intervals of the percentiles of data, the same percentiles_CI of the stats,
+// drawn at the interpolated percentiles.
+func newPercentilesErrorBars(data []float64, percentiles []float64, ci BootstrapConfig) (*plotter.YErrorBars, error) {
+	errs := percentilesErrors{}
+	for _, perc := range percentiles {
+		val, err := stats.Percentile(data, perc)
+		if err != nil {
+			continue
+		}
+		interval, err := PercentileCI(data, perc, ci)
+		if err != nil {
+			continue
+		}
+		errs.XYs = append(errs.XYs, plotter.XY{X: perc, Y: val})
+		errs.YErrors = append(errs.YErrors, struct{ Low, High float64 }{val - interval.Low, interval.High - val})
+	}
+	if len(errs.XYs) == 0 {
+		return nil, errors.New("no confidence intervals")
+	}
+	return plotter.NewYErrorBars(errs)
+}
+
+func percentilesPlotTitle(metric string, ci BootstrapConfig, mark string) string {
+	if ci.Resamples == 0 {
+		return "Percentiles - " + metric + " (Nearest Rank): " + mark
+	}
+	return "Percentiles - " + metric + " (Nearest Rank, " + strconv.FormatFloat(ci.Level, 'f', -1, 64) + "% CI): " + mark
+}
+
+func (p *Probe) GenerateRestartPercentilesPlot(mark string, timeUnit string, genTS string, percentiles []float64, ci BootstrapConfig, plotCfg PlotConfig) ([]string, error) {
+	if restartEvents, hit := p.CollectedRestartRelatedData[mark]; hit {
 		_,
 			evtSeriesMainData,
 			evtSeriesFrontedUpData,
 			evtSeriesFUpMainDelta := GetSplitDataForSingleRestartRelatedData(restartEvents, StrTimeUnit2TimeUnit[timeUnit])
 
+		fNames := []string{}
+
 		//main
 
-		fNameMain := genTS + "_" + p.CurrentMark + "_percentiles_to_main" + ".svg"
+		fBaseMain := genTS + "_" + mark + "_percentiles_to_main"
 
 		{
 			plt := plot.New()
 			plt.Add(plotter.NewGrid())
 
-			plt.Title.Text = "Percentiles - Main (Nearest Rank): " + p.CurrentMark
+			plt.Title.Text = percentilesPlotTitle("Main", ci, mark)
 			plt.X.Label.Text = "Percentile"
 			plt.Y.Label.Text = "Duration: " + timeUnit
 
@@ -148,30 +186,37 @@ func (p *Probe) GenerateRestartPercentilesPlot(timeUnit string, genTS string) (s
 			lpLine, lpPoints, err := plotter.NewLinePoints(pts)
 			if err != nil {
 				Logger.Error("GeneratePercentilesPlot-to-main: NewLinePoints", err.Error())
-				return "", "", "", err
+				return fNames, err
 			}
 			lpLine.Color = plotutil.Color(0)
 			lpPoints.Shape = draw.PyramidGlyph{}
 			lpPoints.Color = plotutil.Color(0)
 
 			plt.Add(lpLine, lpPoints)
+
+			if errBars, err := newPercentilesErrorBars(evtSeriesMainData, percentiles, ci); err == nil {
+				errBars.Color = plotutil.Color(0)
+				plt.Add(errBars)
+			}
 			plt.Legend.Add("to-main", lpLine, lpPoints)
 
-			if err := plt.Save(30*vg.Centimeter, 20*vg.Centimeter, fNameMain); err != nil {
-				Logger.Error("GeneratePlot: Saving plot:", fNameMain)
-				return "", "", "", err
+			fNamesPlot, err := savePlot(plt, fBaseMain, plotCfg)
+			fNames = append(fNames, fNamesPlot...)
+			if err != nil {
+				Logger.Error("GeneratePlot: Saving plot:", fBaseMain)
+				return fNames, err
 			}
 		}
 
-		//fronted-up
+		//frontend-up
 
-		fNameFUp := genTS + "_" + p.CurrentMark + "_percentiles_to_fup" + ".svg"
+		fBaseFUp := genTS + "_" + mark + "_percentiles_to_fup"
 
 		{
 			plt := plot.New()
 			plt.Add(plotter.NewGrid())
 
-			plt.Title.Text = "Percentiles - FrontEndUp (Nearest Rank): " + p.CurrentMark
+			plt.Title.Text = percentilesPlotTitle("FrontEndUp", ci, mark)
 			plt.X.Label.Text = "Percentile"
 			plt.Y.Label.Text = "Duration: " + timeUnit
 
@@ -185,31 +230,38 @@ func (p *Probe) GenerateRestartPercentilesPlot(timeUnit string, genTS string) (s
 
 			lpLine, lpPoints, err := plotter.NewLinePoints(pts)
 			if err != nil {
-				Logger.Error("GeneratePercentilesPlot-to-fronted-up: NewLinePoints", err.Error())
-				return "", "", "", err
+				Logger.Error("GeneratePercentilesPlot-to-frontend-up: NewLinePoints", err.Error())
+				return fNames, err
 			}
 			lpLine.Color = plotutil.Color(1)
 			lpPoints.Shape = draw.PyramidGlyph{}
 			lpPoints.Color = plotutil.Color(1)
 
 			plt.Add(lpLine, lpPoints)
-			plt.Legend.Add("to-fronted-up", lpLine, lpPoints)
 
-			if err := plt.Save(30*vg.Centimeter, 20*vg.Centimeter, fNameFUp); err != nil {
-				Logger.Error("GeneratePlot: Saving plot:", fNameFUp)
-				return "", "", "", err
+			if errBars, err := newPercentilesErrorBars(evtSeriesFrontedUpData, percentiles, ci); err == nil {
+				errBars.Color = plotutil.Color(1)
+				plt.Add(errBars)
+			}
+			plt.Legend.Add("to-frontend-up", lpLine, lpPoints)
+
+			fNamesPlot, err := savePlot(plt, fBaseFUp, plotCfg)
+			fNames = append(fNames, fNamesPlot...)
+			if err != nil {
+				Logger.Error("GeneratePlot: Saving plot:", fBaseFUp)
+				return fNames, err
 			}
 		}
 
-		//fronted-up-main-delta
+		//frontend-up-main-delta
 
-		fNameFUpD := genTS + "_" + p.CurrentMark + "_percentiles_fup_main_delta" + ".svg"
+		fBaseFUpD := genTS + "_" + mark + "_percentiles_fup_main_delta"
 
 		{
 			plt := plot.New()
 			plt.Add(plotter.NewGrid())
 
-			plt.Title.Text = "Percentiles - FrontEndUp-Main-Delta (Nearest Rank): " + p.CurrentMark
+			plt.Title.Text = percentilesPlotTitle("FrontEndUp-Main-Delta", ci, mark)
 			plt.X.Label.Text = "Percentile"
 			plt.Y.Label.Text = "Duration: " + timeUnit
 
@@ -223,47 +275,51 @@ func (p *Probe) GenerateRestartPercentilesPlot(timeUnit string, genTS string) (s
 
 			lpLine, lpPoints, err := plotter.NewLinePoints(pts)
 			if err != nil {
-				Logger.Error("GeneratePercentilesPlot-to-fronted-up: NewLinePoints", err.Error())
-				return "", "", "", err
+				Logger.Error("GeneratePercentilesPlot-frontend-up-main-delta: NewLinePoints", err.Error())
+				return fNames, err
 			}
 			lpLine.Color = plotutil.Color(2)
 			lpPoints.Shape = draw.PyramidGlyph{}
 			lpPoints.Color = plotutil.Color(2)
 
 			plt.Add(lpLine, lpPoints)
-			plt.Legend.Add("to-fronted-up", lpLine, lpPoints)
 
-			if err := plt.Save(30*vg.Centimeter, 20*vg.Centimeter, fNameFUpD); err != nil {
-				Logger.Error("GeneratePlot: Saving plot:", fNameFUpD)
-				return "", "", "", err
+			if errBars, err := newPercentilesErrorBars(evtSeriesFUpMainDelta, percentiles, ci); err == nil {
+				errBars.Color = plotutil.Color(2)
+				plt.Add(errBars)
+			}
+			plt.Legend.Add("frontend-up-main-delta", lpLine, lpPoints)
+
+			fNamesPlot, err := savePlot(plt, fBaseFUpD, plotCfg)
+			fNames = append(fNames, fNamesPlot...)
+			if err != nil {
+				Logger.Error("GeneratePlot: Saving plot:", fBaseFUpD)
+				return fNames, err
 			}
 		}
 
-		return fNameMain, fNameFUp, fNameFUpD, nil
+		return fNames, nil
 
 	} else {
-		Logger.Error("GeneratePercentilesPlot: no series with mark:", p.CurrentMark)
-		return "", "", "", errors.New("no series with mark")
+		Logger.Error("GeneratePercentilesPlot: no series with mark:", mark)
+		return nil, errors.New("no series with mark")
 	}
 }
 
-func (p *Probe) GenerateS3WorkloadRawDataPlot(timeUnit string, genTS string) (string, error) {
+func (p *Probe) GenerateS3WorkloadRawDataPlot(mark string, timeUnit string, genTS string, plotCfg PlotConfig) ([]string, error) {
 
-	if s3WLEvents, hit := p.CollectedS3WorkloadRelatedData[p.CurrentMark]; hit {
+	tU := StrTimeUnit2TimeUnit[timeUnit]
+	if evtSeries, evtSeriesRTTData, hit := p.s3WorkloadEntries(mark, p.CollectedRestartRelatedData[mark], tU); hit && len(evtSeries) > 0 {
 
-		tU := StrTimeUnit2TimeUnit[timeUnit]
 		plt := plot.New()
 		plt.Add(plotter.NewGrid())
 
-		plt.Title.Text = "RTT S3Workload: " + p.CurrentMark
+		plt.Title.Text = "RTT S3Workload: " + mark
 		plt.X.Label.Text = "Time"
 		plt.Y.Label.Text = "RTT: " + timeUnit
 
-		evtSeries,
-			evtSeriesRTTData := GetSplitDataForSingleS3WorkloadRelatedData(s3WLEvents, tU)
-
 		//Draw correlated restart durations (yellow)
-		if restartEvents, hit := p.CollectedRestartRelatedData[p.CurrentMark]; hit {
+		if restartEvents, hit := p.CollectedRestartRelatedData[mark]; hit {
 			var maxRTT float64 = 0
 			maxRTT, _ = stats.Max(evtSeriesRTTData)
 
@@ -277,20 +333,19 @@ func (p *Probe) GenerateS3WorkloadRawDataPlot(timeUnit string, genTS string) (st
 				pts[1].Y = maxRTT / 2
 
 				//Draw correlated interval before first successful operation (cyan)
-				for fIt := s3WLEvents.UpperBound(it.StartFrontendUp.Ts); fIt.Valid(); fIt.Next() {
-					val := fIt.Value()
-					if val.Error == nil {
+				for _, val := range evtSeries[sort.Search(len(evtSeries), func(i int) bool { return evtSeries[i].Start > it.StartFrontendUp.Ts }):] {
+					if val.ErrDesc == "" {
 						pts := make(plotter.XYs, 2)
 						pts[0].X = float64(((it.StartFrontendUp.Ts - evtSeries[0].Start) / tU))
 						pts[0].Y = maxRTT / 2
 
-						pts[1].X = float64(((val.StartTs - evtSeries[0].Start) / tU))
+						pts[1].X = float64(((val.Start - evtSeries[0].Start) / tU))
 						pts[1].Y = maxRTT / 2
 
 						line, points, err := plotter.NewLinePoints(pts)
 						if err != nil {
 							Logger.Error("GenerateS3WorkloadRawDataPlot: NewLinePoints", err.Error())
-							return "", err
+							return nil, err
 						}
 
 						line.Color = plotutil.Color(2)
@@ -305,7 +360,7 @@ func (p *Probe) GenerateS3WorkloadRawDataPlot(timeUnit string, genTS string) (st
 				line, points, err := plotter.NewLinePoints(pts)
 				if err != nil {
 					Logger.Error("GenerateS3WorkloadRawDataPlot: NewLinePoints", err.Error())
-					return "", err
+					return nil, err
 				}
 
 				line.Color = plotutil.Color(3)
@@ -323,19 +378,255 @@ func (p *Probe) GenerateS3WorkloadRawDataPlot(timeUnit string, genTS string) (st
 				Logger.Errorf("NewVBars: %s", err.Error())
 			}
 			plt.Add(bars)
+			bars.AddLegend(plt)
 		}
 
-		fName := genTS + "_" + p.CurrentMark + "_S3WL_RTT_raw" + ".svg"
+		fBase := genTS + "_" + mark + "_S3WL_RTT_raw"
 
-		if err := plt.Save(30*vg.Centimeter, 20*vg.Centimeter, fName); err != nil {
-			Logger.Errorf("GenerateS3WorkloadRawDataPlot: Saving plot: %s", fName)
-			return "", err
+		fNames, err := savePlot(plt, fBase, plotCfg)
+		if err != nil {
+			Logger.Errorf("GenerateS3WorkloadRawDataPlot: Saving plot: %s", fBase)
 		}
-
-		return fName, nil
+		return fNames, err
 
 	} else {
-		Logger.Errorf("GenerateS3WorkloadRawDataPlot: no series with mark: %s", p.CurrentMark)
-		return "", errors.New("no series with mark")
+		Logger.Errorf("GenerateS3WorkloadRawDataPlot: no series with mark: %s", mark)
+		return nil, errors.New("no series with mark")
+	}
+}
+
+func (p *Probe) GenerateSweepPlot(timeUnit string, genTS string, percentiles []float64, plotCfg PlotConfig) ([]string, error) {
+	sweep := p.CurrentSweep
+	if sweep == nil || len(sweep.StepMarks) == 0 {
+		Logger.Error("GenerateSweepPlot: no sweep steps")
+		return nil, errors.New("no sweep steps")
+	}
+
+	plt := plot.New()
+	plt.Add(plotter.NewGrid())
+
+	plt.Title.Text = "Restart Percentiles (Nearest Rank) vs Dataset Size: " + sweep.BaseMark
+	plt.X.Label.Text = "Objects"
+	plt.Y.Label.Text = "Duration: " + timeUnit
+
+	mainPts := make([]plotter.XYs, len(percentiles))
+	fUpPts := make([]plotter.XYs, len(percentiles))
+
+	for stepIdx, mark := range sweep.StepMarks {
+		restartEvents, hit := p.CollectedRestartRelatedData[mark]
+		if !hit {
+			continue
+		}
+		_,
+			evtSeriesMainData,
+			evtSeriesFrontedUpData,
+			_ := GetSplitDataForSingleRestartRelatedData(restartEvents, StrTimeUnit2TimeUnit[timeUnit])
+
+		for i, perc := range percentiles {
+			if val, err := stats.PercentileNearestRank(evtSeriesMainData, perc); err == nil {
+				mainPts[i] = append(mainPts[i], plotter.XY{X: float64(sweep.StepObjCounts[stepIdx]), Y: val})
+			}
+			if val, err := stats.PercentileNearestRank(evtSeriesFrontedUpData, perc); err == nil {
+				fUpPts[i] = append(fUpPts[i], plotter.XY{X: float64(sweep.StepObjCounts[stepIdx]), Y: val})
+			}
+		}
+	}
+
+	for i, perc := range percentiles {
+		percKey := PercentileKey(perc)
+		for j, pts := range []plotter.XYs{mainPts[i], fUpPts[i]} {
+			if len(pts) == 0 {
+				continue
+			}
+			lpLine, lpPoints, err := plotter.NewLinePoints(pts)
+			if err != nil {
+				Logger.Error("GenerateSweepPlot: NewLinePoints", err.Error())
+				return nil, err
+			}
+			lpLine.Color = plotutil.Color(i)
+			lpLine.Dashes = plotutil.Dashes(j)
+			lpPoints.Shape = draw.PyramidGlyph{}
+			lpPoints.Color = plotutil.Color(i)
+
+			plt.Add(lpLine, lpPoints)
+			if j == 0 {
+				plt.Legend.Add(percKey+"-to-main", lpLine, lpPoints)
+			} else {
+				plt.Legend.Add(percKey+"-to-frontend-up", lpLine, lpPoints)
+			}
+		}
+	}
+
+	fBase := genTS + "_" + sweep.BaseMark + "_sweep"
+
+	fNames, err := savePlot(plt, fBase, plotCfg)
+	if err != nil {
+		Logger.Error("GenerateSweepPlot: Saving plot:", fBase)
+	}
+	return fNames, err
+}
+
+func addPercentilesCurve(plt *plot.Plot, data []float64, legend string, color int, dashes int) error {
+	pts := make(plotter.XYs, 0, 100)
+	for i := 1; i <= 100; i++ {
+		if val, err := stats.PercentileNearestRank(data, float64(i)); err == nil {
+			pts = append(pts, plotter.XY{X: float64(i), Y: val})
+		}
+	}
+	if len(pts) == 0 {
+		return errors.New("no data")
 	}
+
+	lpLine, lpPoints, err := plotter.NewLinePoints(pts)
+	if err != nil {
+		return err
+	}
+	lpLine.Color = plotutil.Color(color)
+	lpLine.Dashes = plotutil.Dashes(dashes)
+	lpPoints.Shape = draw.PyramidGlyph{}
+	lpPoints.Color = plotutil.Color(color)
+
+	plt.Add(lpLine, lpPoints)
+	plt.Legend.Add(legend, lpLine, lpPoints)
+	return nil
+}
+
+// GenerateCompareOverlayPlot overlays the percentiles of the base and candidate marks:
+// one plot for the restart durations and, when both marks have it, one for the workload RTT.
+func (p *Probe) GenerateCompareOverlayPlot(cfg CompareConfig, genTS string, plotCfg PlotConfig) ([]string, error) {
+	baseRestarts, hitBase := p.CollectedRestartRelatedData[cfg.Base]
+	candRestarts, hitCand := p.CollectedRestartRelatedData[cfg.Candidate]
+	if !hitBase || !hitCand {
+		Logger.Error("GenerateCompareOverlayPlot: no series for base or candidate mark")
+		return nil, errors.New("no series with mark")
+	}
+	tu := StrTimeUnit2TimeUnit[cfg.TimeUnit]
+	fNames := []string{}
+
+	{
+		plt := plot.New()
+		plt.Add(plotter.NewGrid())
+
+		plt.Title.Text = "Percentiles (Nearest Rank): " + cfg.Base + " vs " + cfg.Candidate
+		plt.X.Label.Text = "Percentile"
+		plt.Y.Label.Text = "Duration: " + cfg.TimeUnit
+
+		for i, restartEvents := range [][]RestartEvent{baseRestarts, candRestarts} {
+			mark := []string{cfg.Base, cfg.Candidate}[i]
+			_,
+				evtSeriesMainData,
+				evtSeriesFrontedUpData,
+				_ := GetSplitDataForSingleRestartRelatedData(restartEvents, tu)
+
+			if err := addPercentilesCurve(plt, evtSeriesMainData, mark+"-to-main", i, 1); err != nil {
+				Logger.Error("GenerateCompareOverlayPlot-to-main:", err.Error())
+			}
+			if err := addPercentilesCurve(plt, evtSeriesFrontedUpData, mark+"-to-frontend-up", i, 0); err != nil {
+				Logger.Error("GenerateCompareOverlayPlot-to-frontend-up:", err.Error())
+			}
+		}
+
+		fBase := genTS + "_" + cfg.Base + "_vs_" + cfg.Candidate + "_compare_restart"
+
+		fNamesPlot, err := savePlot(plt, fBase, plotCfg)
+		fNames = append(fNames, fNamesPlot...)
+		if err != nil {
+			Logger.Error("GenerateCompareOverlayPlot: Saving plot:", fBase)
+			return fNames, err
+		}
+	}
+
+	_, baseRTT, hitBaseWL := p.s3WorkloadEntries(cfg.Base, baseRestarts, tu)
+	_, candRTT, hitCandWL := p.s3WorkloadEntries(cfg.Candidate, candRestarts, tu)
+	if hitBaseWL && hitCandWL {
+		plt := plot.New()
+		plt.Add(plotter.NewGrid())
+
+		plt.Title.Text = "S3 Workload RTT Percentiles (Nearest Rank): " + cfg.Base + " vs " + cfg.Candidate
+		plt.X.Label.Text = "Percentile"
+		plt.Y.Label.Text = "RTT: " + cfg.TimeUnit
+
+		if err := addPercentilesCurve(plt, baseRTT, cfg.Base+"-RTT", 0, 0); err != nil {
+			Logger.Error("GenerateCompareOverlayPlot-RTT:", err.Error())
+		}
+		if err := addPercentilesCurve(plt, candRTT, cfg.Candidate+"-RTT", 1, 0); err != nil {
+			Logger.Error("GenerateCompareOverlayPlot-RTT:", err.Error())
+		}
+
+		fBase := genTS + "_" + cfg.Base + "_vs_" + cfg.Candidate + "_compare_S3WL_RTT"
+
+		fNamesPlot, err := savePlot(plt, fBase, plotCfg)
+		fNames = append(fNames, fNamesPlot...)
+		if err != nil {
+			Logger.Error("GenerateCompareOverlayPlot: Saving plot:", fBase)
+			return fNames, err
+		}
+	}
+
+	return fNames, nil
+}
+
+// GenerateMarksOverlayPlot overlays the percentiles of the marks: one plot
+// for the restart durations and, when there is raw workload data, one for the workload RTT.
+func (p *Probe) GenerateMarksOverlayPlot(marks []string, name string, timeUnit string, genTS string, plotCfg PlotConfig) ([]string, error) {
+	toMain, toFUp, _ := p.restartDistSeries(marks, timeUnit)
+	rtt := p.s3WorkloadDistSeries(marks, timeUnit)
+	if len(toMain) == 0 && len(rtt) == 0 {
+		Logger.Errorf("GenerateMarksOverlayPlot: no series with marks: %s", strings.Join(marks, ","))
+		return nil, errors.New("no series with mark")
+	}
+	fNames := []string{}
+
+	if len(toMain) > 0 {
+		plt := plot.New()
+		plt.Add(plotter.NewGrid())
+
+		plt.Title.Text = "Percentiles (Nearest Rank): " + strings.Join(marks, ", ")
+		plt.X.Label.Text = "Percentile"
+		plt.Y.Label.Text = "Duration: " + timeUnit
+
+		for i := range toMain {
+			if err := addPercentilesCurve(plt, toMain[i].Data, toMain[i].Mark+"-to-main", i, 1); err != nil {
+				Logger.Errorf("GenerateMarksOverlayPlot-to-main: %s", err.Error())
+			}
+			if err := addPercentilesCurve(plt, toFUp[i].Data, toFUp[i].Mark+"-to-frontend-up", i, 0); err != nil {
+				Logger.Errorf("GenerateMarksOverlayPlot-to-frontend-up: %s", err.Error())
+			}
+		}
+
+		fBase := genTS + "_" + name + "_overlay_restart"
+
+		fNamesPlot, err := savePlot(plt, fBase, plotCfg)
+		fNames = append(fNames, fNamesPlot...)
+		if err != nil {
+			Logger.Errorf("GenerateMarksOverlayPlot: Saving plot: %s", fBase)
+			return fNames, err
+		}
+	}
+
+	if len(rtt) > 0 {
+		plt := plot.New()
+		plt.Add(plotter.NewGrid())
+
+		plt.Title.Text = "S3 Workload RTT Percentiles (Nearest Rank): " + strings.Join(marks, ", ")
+		plt.X.Label.Text = "Percentile"
+		plt.Y.Label.Text = "RTT: " + timeUnit
+
+		for i := range rtt {
+			if err := addPercentilesCurve(plt, rtt[i].Data, rtt[i].Mark+"-RTT", i, 0); err != nil {
+				Logger.Errorf("GenerateMarksOverlayPlot-RTT: %s", err.Error())
+			}
+		}
+
+		fBase := genTS + "_" + name + "_overlay_S3WL_RTT"
+
+		fNamesPlot, err := savePlot(plt, fBase, plotCfg)
+		fNames = append(fNames, fNamesPlot...)
+		if err != nil {
+			Logger.Errorf("GenerateMarksOverlayPlot: Saving plot: %s", fBase)
+			return fNames, err
+		}
+	}
+
+	return fNames, nil
 }
@@ -13,19 +13,21 @@ package utils
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/gin-gonic/gin"
 	"github.com/igrmk/treemap/v2"
-	"github.com/montanaflynn/stats"
+	"go.opentelemetry.io/otel/attribute"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -52,16 +54,22 @@ var StrTimeUnit2TimeUnit = map[string]int64{
 type RestartRelatedData map[string][]RestartEvent
 
 type S3WorkloadConfig struct {
-	Client    *s3.S3
+	Client    *s3.S3 `json:"-"`
 	FuncName  string
 	FuncArgs  map[string]string
 	Frequency uint //msec
+	Policy    S3ClientPolicy
+	KeepRaw   bool //keep every event, latencies are always recorded in histograms
+	Ingress   bool //through the ingress endpoint
 }
 
 func (cfg *S3WorkloadConfig) Reset() {
 	cfg.Client = nil
 	cfg.FuncName = ""
 	cfg.Frequency = 0
+	cfg.Policy.Reset()
+	cfg.KeepRaw = true
+	cfg.Ingress = false
 	if cfg.FuncArgs != nil {
 		for k := range cfg.FuncArgs {
 			delete(cfg.FuncArgs, k)
@@ -102,35 +110,72 @@ type Probe struct {
 	CurrentPendingRestarts   uint
 	CurrentGracePeriod       uint
 	CurrentLieDownPeriod     uint
+	CurrentSettlePeriod      uint
 	CurrentDeathType         string
 	CurrentMark              string
+	CurrentRunId             string //identifies the campaign, assigned at its start
 	CurrentId                int
-	CurrentInterposeFunc     func(*gin.Context)
-	CurrentGinCtx            *gin.Context
+	CurrentHooks             []Hook
 	CurrentNodeNameList      *[]string
 	CurrentNodeNameActiveIdx uint
 	CurrentSelectedNode      string
 	CurrentSelectedNodeSet   bool
-
-	CurrentS3WorkloadCfg     S3WorkloadConfig
-	CurrentS3WorkloadStarted bool
-	CurrentS3WorkloadId      int
+	CurrentSweep             *SweepConfig
+	CurrentPercentiles       []float64
+	CurrentCI                BootstrapConfig
+	CurrentSLOs              []SLO
+	CurrentOutliers          OutlierConfig
+	CurrentPlot              PlotConfig
+
+	CurrentS3WorkloadCfg        S3WorkloadConfig
+	CurrentS3WorkloadStarted    bool
+	CurrentS3WorkloadId         int
+	CurrentS3WorkloadErrorBurst *S3WorkloadErrorBurst
 
 	CollectedRestartRelatedData    RestartRelatedData
 	CollectedS3WorkloadRelatedData S3WorkloadRelatedData
+	CollectedS3WorkloadHistograms  S3WorkloadHistograms
+	CollectedS3WorkloadErrors      S3WorkloadErrorCounts
+	CollectedS3WorkloadIntegrity   S3WorkloadIntegrity
+
+	ImportedMarks map[string]string //read-only mark -> source stats object
+
+	LastSLOVerdict *SLOVerdict
+
+	Store *RunStore
+
+	CurrentCycleTrace *CycleTrace
+	TraceMtx          sync.Mutex
 
 	S3WorkloadEvtChan chan string
+	S3WorkloadMtx     sync.Mutex
+}
+
+// InitClient sets the client of the workload for its endpoint,
+// with its own client when the policy is not the default one.
+func (cfg *S3WorkloadConfig) InitClient() {
+	endpoint := Cfg.S3GWEndpoint
+	cfg.Client = S3Client_S3GW
+	if cfg.Ingress {
+		endpoint = Cfg.S3GWEndpointIngress
+		cfg.Client = S3Client_S3GW_ingress
+	}
+
+	if !cfg.Policy.IsDefault() {
+		cfg.Client = InitS3Client_WithPolicy(endpoint, Cfg.S3GWS3ForcePathStyle, cfg.Policy)
+	}
 }
 
 func (p *Probe) ResetCurrentState() {
 	p.CurrentPendingRestarts = 0
 	p.CurrentGracePeriod = 0
 	p.CurrentLieDownPeriod = 0
+	p.CurrentSettlePeriod = Cfg.SettleMSecsAfterFrontendUp
 	p.CurrentDeathType = ""
 	p.CurrentMark = ""
+	p.CurrentRunId = ""
 	p.CurrentId = 0
-	p.CurrentInterposeFunc = nil
-	p.CurrentGinCtx = nil
+	p.CurrentHooks = nil
 	if p.CurrentNodeNameList != nil {
 		p.SetK8sScheduleAllNodes()
 	}
@@ -138,6 +183,12 @@ func (p *Probe) ResetCurrentState() {
 	p.CurrentNodeNameActiveIdx = 0
 	p.CurrentSelectedNode = ""
 	p.CurrentSelectedNodeSet = false
+	p.CurrentSweep = nil
+	p.CurrentPercentiles = DefaultPercentiles
+	p.CurrentCI = DefaultBootstrapConfig
+	p.CurrentSLOs = nil
+	p.CurrentOutliers = DefaultOutlierConfig()
+	p.CurrentPlot = DefaultPlotConfig()
 
 	p.CurrentS3WorkloadCfg.Reset()
 	p.CurrentS3WorkloadStarted = false
@@ -149,9 +200,25 @@ func (p *Probe) Clear() {
 	for k := range p.CollectedRestartRelatedData {
 		delete(p.CollectedRestartRelatedData, k)
 	}
+	p.S3WorkloadMtx.Lock()
 	for k := range p.CollectedS3WorkloadRelatedData {
 		delete(p.CollectedS3WorkloadRelatedData, k)
 	}
+	for k := range p.CollectedS3WorkloadHistograms {
+		delete(p.CollectedS3WorkloadHistograms, k)
+	}
+	for k := range p.CollectedS3WorkloadErrors {
+		delete(p.CollectedS3WorkloadErrors, k)
+	}
+	for k := range p.CollectedS3WorkloadIntegrity {
+		delete(p.CollectedS3WorkloadIntegrity, k)
+	}
+	p.S3WorkloadMtx.Unlock()
+	for k := range p.ImportedMarks {
+		delete(p.ImportedMarks, k)
+	}
+	p.LastSLOVerdict = nil
+	p.Store.Reset()
 }
 
 func (p *Probe) SubmitDeath(evt *DeathEvent) {
@@ -160,6 +227,9 @@ func (p *Probe) SubmitDeath(evt *DeathEvent) {
 		return
 	}
 	p.CurrentDeath = evt
+	LiveFeed.Publish(LiveEventDeath, p.CurrentMark, evt)
+	SetProbeState(ProbeStateRestarting)
+	p.Store.Append(&StoreRecord{Kind: StoreRecordDeath, Mark: p.CurrentMark, Death: evt})
 }
 
 func (p *Probe) SubmitStart(evt *StartEvent) {
@@ -168,18 +238,23 @@ func (p *Probe) SubmitStart(evt *StartEvent) {
 		return
 	}
 	p.CurrentStartList = append(p.CurrentStartList, evt)
+	p.Store.Append(&StoreRecord{Kind: StoreRecordStart, Mark: p.CurrentMark, Start: evt})
+	LiveFeed.Publish(LiveEventStart, p.CurrentMark, evt)
 
 	if evt.Where == Cfg.CollectRestartAtEvent {
 		p.submitRestart()
+		p.RunHooks(HookPhasePostFrontendUp)
 		if p.CurrentPendingRestarts > 0 {
+			SetProbeState(ProbeStateRunning)
+			p.startCycleTrace()
 			time.Sleep(time.Duration(Cfg.WaitMSecsBeforeTriggerDeath) * time.Millisecond)
 			if p.CurrentGracePeriod > 0 {
 				Logger.Infof("GRACE - waiting %d ms...", p.CurrentGracePeriod)
+				endSpan := p.traceSpan("grace")
 				time.Sleep(time.Duration(p.CurrentGracePeriod) * time.Millisecond)
+				endSpan(nil)
 			}
-			if p.CurrentInterposeFunc != nil && p.CurrentGinCtx != nil {
-				p.CurrentInterposeFunc(p.CurrentGinCtx)
-			}
+			p.RunHooks(HookPhasePreDeath)
 			p.RequestDie()
 		} else {
 
@@ -189,37 +264,116 @@ func (p *Probe) SubmitStart(evt *StartEvent) {
 			}
 
 			if p.CurrentMark == "unsolicited" {
+				SetProbeState(ProbeStateIdle)
 				return
 			}
 
 			//wrap up results and send those to S3
 
+			SetProbeState(ProbeStateWrapUp)
+
 			timeUnit := "ms"
 			genTS := strconv.Itoa(int(time.Now().Unix()))
-			stats := Stats{TimeUnit: timeUnit}
+			stats := Stats{TimeUnit: timeUnit, Percentiles: p.CurrentPercentiles, CI: p.CurrentCI, Outliers: p.CurrentOutliers, Plot: p.CurrentPlot}
+
+			marks := []string{p.CurrentMark}
+			if p.CurrentSweep != nil {
+				marks = p.CurrentSweep.StepMarks
+			}
+			for _, mark := range marks {
+				p.ComputeRestartStats(&stats, mark, timeUnit, true)
+				p.ComputeS3WorkloadStats(&stats, mark, timeUnit, true)
+				p.ComputeS3WorkloadStats(&stats, BurstMark(mark), timeUnit, true)
+			}
 
-			p.ComputeRestartStats(&stats, p.CurrentMark, timeUnit, true)
-			p.ComputeS3WorkloadStats(&stats, p.CurrentMark, timeUnit, true)
+			if len(p.CurrentSLOs) > 0 {
+				stats.SLO = EvaluateSLOs(p.CurrentSLOs, &stats, p.CurrentMark)
+				p.LastSLOVerdict = stats.SLO
+				Logger.Infof("SLO - verdict: %s", stats.SLO.Status)
+			}
+
+			name := p.CurrentMark
+			if p.CurrentSweep != nil {
+				name = p.CurrentSweep.BaseMark
+			}
+			var fSweep []string
+			if p.CurrentSweep != nil {
+				fSweep, _ = p.GenerateSweepPlot(timeUnit, genTS, stats.Percentiles, stats.Plot)
+			}
+			fNames := p.Render(genTS, timeUnit, name, marks, stats, fSweep)
 
 			Logger.Infof("Saving generated artifacts ...")
-			SendStatsArtifactsToS3(S3Client_SaveData, Cfg.SaveDataBucket, p.Render(genTS, timeUnit, stats))
+			SendStatsArtifactsToS3(S3Client_SaveData, Cfg.SaveDataBucket, fNames)
 			Logger.Infof("Saved")
 
+			p.Store.Append(&StoreRecord{Kind: StoreRecordCampaignEnd, Mark: p.CurrentMark})
 			p.ResetCurrentState()
+			SetProbeState(ProbeStateIdle)
+		}
+	}
+}
+
+// RenderMarks returns the sorted marks with collected raw data matching markPar,
+// all of them for all.
+func (p *Probe) RenderMarks(markPar string) []string {
+	marks := []string{}
+	for mark := range p.CollectedRestartRelatedData {
+		if markPar == "all" || markPar == mark {
+			marks = append(marks, mark)
+		}
+	}
+	p.S3WorkloadMtx.Lock()
+	for mark := range p.CollectedS3WorkloadRelatedData {
+		if (markPar == "all" || markPar == mark) && !containsString(marks, mark) {
+			marks = append(marks, mark)
 		}
 	}
+	p.S3WorkloadMtx.Unlock()
+	sort.Strings(marks)
+	return marks
 }
 
-func (p *Probe) Render(genTS string, timeUnit string, stats Stats) []string {
+// Render saves the stats and renders the per-mark plots of every mark and,
+// for more marks, the plots overlaying them; the artifacts of the whole
+// set are named after name. fPlots, already rendered by the caller, are
+// added to the set and to the report.
+func (p *Probe) Render(genTS string, timeUnit string, name string, marks []string, stats Stats, fPlots []string) []string {
 	fNames := []string{}
 
-	fStat, _ := p.SaveStats(genTS, stats)
-	fRestartRaw, _ := p.GenerateRestartRawDataPlot(timeUnit, genTS)
-	fRestartP1, fRestartP2, fRestartP3, _ := p.GenerateRestartPercentilesPlot(timeUnit, genTS)
+	fStat, _ := p.SaveStats(genTS, name, stats)
+	fNames = append(fNames, fStat)
 
-	fS3WLRaw, _ := p.GenerateS3WorkloadRawDataPlot(timeUnit, genTS)
+	for _, mark := range marks {
+		fRestartRaw, _ := p.GenerateRestartRawDataPlot(mark, timeUnit, genTS, stats.Plot)
+		fRestartPs, _ := p.GenerateRestartPercentilesPlot(mark, timeUnit, genTS, stats.Percentiles, stats.CI, stats.Plot)
 
-	fNames = append(fNames, fStat, fRestartRaw, fRestartP1, fRestartP2, fRestartP3, fS3WLRaw)
+		fS3WLRaw, _ := p.GenerateS3WorkloadRawDataPlot(mark, timeUnit, genTS, stats.Plot)
+		fS3WLHist, _ := p.SaveS3WorkloadHistogramLog(mark, genTS)
+		fTimeline, _ := p.GenerateTimelinePlot(mark, timeUnit, genTS, stats.Plot)
+
+		fNames = append(fNames, fRestartRaw...)
+		fNames = append(fNames, fRestartPs...)
+		fNames = append(fNames, fS3WLRaw...)
+		if fS3WLHist != "" {
+			fNames = append(fNames, fS3WLHist)
+		}
+		fNames = append(fNames, fTimeline...)
+	}
+
+	if len(marks) > 1 {
+		fOverlay, _ := p.GenerateMarksOverlayPlot(marks, name, timeUnit, genTS, stats.Plot)
+		fNames = append(fNames, fOverlay...)
+	}
+	fCDFs, _ := p.GenerateCDFPlots(marks, name, timeUnit, genTS, stats.Plot)
+	fHists, _ := p.GenerateHistogramPlots(marks, name, timeUnit, genTS, stats.Plot)
+	fNames = append(fNames, fCDFs...)
+	fNames = append(fNames, fHists...)
+	fNames = append(fNames, fPlots...)
+	fNames = append(fNames, p.ExportRawSeries(genTS, name, &stats)...)
+
+	if fReport, err := p.GenerateHTMLReport(genTS, name, &stats, fNames); err == nil {
+		fNames = append(fNames, fReport)
+	}
 
 	return fNames
 }
@@ -233,40 +387,76 @@ func (p *Probe) findStartEvent(where string) *StartEvent {
 	return nil
 }
 
+// currentNode returns the node the radosgw is scheduled on when the probe selects it.
+func (p *Probe) currentNode() string {
+	if p.CurrentNodeNameList != nil && len(*p.CurrentNodeNameList) > 0 {
+		return (*p.CurrentNodeNameList)[p.CurrentNodeNameActiveIdx]
+	}
+	return p.CurrentSelectedNode
+}
+
+// nextRestartId returns the id of the next restart of the mark, following
+// the restarts already collected for it so that a further campaign on the
+// same mark does not reuse their ids.
+func (p *Probe) nextRestartId(mark string) int {
+	id := 0
+	for _, evt := range p.CollectedRestartRelatedData[mark] {
+		if evt.Id > id {
+			id = evt.Id
+		}
+	}
+	return id + 1
+}
+
 func (p *Probe) submitRestart() {
 	if p.CurrentMark == "" {
 		p.CurrentMark = "unsolicited"
 	}
 
-	p.CurrentId = p.CurrentId + 1
+	p.CurrentId = p.nextRestartId(p.CurrentMark)
+	if p.CurrentSweep != nil {
+		p.CurrentSweep.Restarts++
+	}
 
 	p.CollectedRestartRelatedData[p.CurrentMark] = append(p.CollectedRestartRelatedData[p.CurrentMark],
 		RestartEvent{Death: p.CurrentDeath,
 			StartMain:       p.findStartEvent("main"),
 			StartFrontendUp: p.findStartEvent("frontend-up"),
+			SettlePeriod:    p.CurrentSettlePeriod,
+			Node:            p.currentNode(),
+			How:             p.CurrentDeathType,
+			RunId:           p.CurrentRunId,
 			Id:              p.CurrentId})
 
 	restartEvt := &p.CollectedRestartRelatedData[p.CurrentMark][len(p.CollectedRestartRelatedData[p.CurrentMark])-1]
 
+	missingMain, missingFrontendUp := restartEvt.StartMain == nil, restartEvt.StartFrontendUp == nil
+
 	if restartEvt.StartMain == nil {
 		restartEvt.StartMain = &StartEvent{Ts: restartEvt.Death.Ts}
 	}
 	if restartEvt.StartFrontendUp == nil {
 		restartEvt.StartFrontendUp = &StartEvent{Ts: restartEvt.Death.Ts}
 	}
+	restartEvt.K8sEvents = captureRestartEvents(restartEvt, p.CurrentOutliers.EventNamespaces)
+
+	p.Store.Append(&StoreRecord{Kind: StoreRecordRestart, Mark: p.CurrentMark, Restart: restartEvt})
+	observeRestart(p.CurrentMark, restartEvt, missingMain, missingFrontendUp)
+	p.endCycleTrace(restartEvt, missingMain, missingFrontendUp)
 
 	Logger.Infof("inserted restart event: mark:%s, death:%d, start-main:%d, start-f-up:%d; collected events:%d",
 		p.CurrentMark,
 		p.CurrentDeath.Ts,
 		restartEvt.StartMain.Ts,
 		restartEvt.StartFrontendUp.Ts,
-		p.CurrentId)
+		len(p.CollectedRestartRelatedData[p.CurrentMark]))
 
 	if p.CurrentPendingRestarts > 0 {
 		p.CurrentPendingRestarts = p.CurrentPendingRestarts - 1
 	}
 
 	Logger.Infof("pending restarts: %d", p.CurrentPendingRestarts)
+	p.publishRestart(restartEvt, missingMain, missingFrontendUp)
 	p.CurrentDeath = nil
 	p.CurrentStartList = nil
 }
@@ -304,6 +494,8 @@ func (p *Probe) AskK8sScaleDeployment_0_1() {
 		time.Sleep(time.Duration(Cfg.WaitMSecsBeforeSetReplicas1) * time.Millisecond)
 	}
 
+	p.RunHooks(HookPhaseLieDown)
+
 	if p.CurrentLieDownPeriod > 0 {
 		Logger.Infof("LIE-DOWN - waiting %d ms...", p.CurrentLieDownPeriod)
 		time.Sleep(time.Duration(p.CurrentLieDownPeriod) * time.Millisecond)
@@ -347,6 +539,10 @@ func (p *Probe) RequestDie() {
 		}
 	}
 
+	p.startCycleTrace()
+	endSpan := p.traceSpan("death-action", attribute.String("death_type", p.CurrentDeathType))
+	defer endSpan(nil)
+
 	if p.CurrentSelectedNode != "" && !p.CurrentSelectedNodeSet {
 		p.SetK8sNoScheduleAllNodes()
 		K8sCli.UnsetTaint(p.CurrentSelectedNode, "noSch", "1", v1.TaintEffectNoSchedule)
@@ -371,6 +567,81 @@ func (p *Probe) RequestDie() {
 	}
 }
 
+// collectS3WorkloadEvent adds a S3 workload event to the collected data of the mark.
+func (p *Probe) collectS3WorkloadEvent(mark string, op string, evt *S3WorkloadEvent, keepRaw bool) {
+	evt.Op = op
+	p.CollectedS3WorkloadHistograms.Record(mark, op, evt.EndTs-evt.StartTs)
+	if evt.ErrCat != ErrCatNone {
+		p.CollectedS3WorkloadErrors.Record(mark, evt.ErrCat)
+	}
+
+	if !keepRaw {
+		return
+	}
+
+	if p.CollectedS3WorkloadRelatedData[mark] == nil {
+		p.CollectedS3WorkloadRelatedData[mark] = treemap.New[int64, S3WorkloadEvent]()
+	}
+	p.CollectedS3WorkloadRelatedData[mark].Set(evt.StartTs, *evt)
+}
+
+// recordS3WorkloadEvent stores the outcome of a S3 operation
+// performed by a workload in the series of the mark.
+func (p *Probe) recordS3WorkloadEvent(mark string, op string, start int64, end int64, size int64, retries int, err error) {
+	errCode, httpStatus := GetErrorCode(err)
+
+	p.S3WorkloadMtx.Lock()
+	defer p.S3WorkloadMtx.Unlock()
+
+	p.CurrentS3WorkloadId++
+
+	s3WorkloadEvent := S3WorkloadEvent{Id: p.CurrentS3WorkloadId,
+		RunId:      p.CurrentRunId,
+		StartTs:    start,
+		EndTs:      end,
+		Size:       size,
+		Error:      err,
+		Retries:    retries,
+		ErrCode:    errCode,
+		HTTPStatus: httpStatus,
+		ErrCat:     ClassifyError(err, errCode, httpStatus)}
+
+	p.collectS3WorkloadEvent(mark, op, &s3WorkloadEvent, p.CurrentS3WorkloadCfg.KeepRaw)
+	observeS3WorkloadEvent(mark, op, &s3WorkloadEvent)
+	p.traceS3WorkloadEvent(op, &s3WorkloadEvent)
+	if mark == p.CurrentMark {
+		p.trackS3WorkloadErrorBurst(op, &s3WorkloadEvent)
+	}
+	p.Store.Append(&StoreRecord{Kind: StoreRecordS3Workload,
+		Mark:    mark,
+		Op:      op,
+		S3WL:    &s3WorkloadEvent,
+		KeepRaw: p.CurrentS3WorkloadCfg.KeepRaw})
+
+	if !p.CurrentS3WorkloadCfg.KeepRaw {
+		return
+	}
+
+	if p.CurrentS3WorkloadId%100 == 0 {
+		Logger.Infof("CollectedS3WorkloadRelatedData[%s] %d", mark, p.CollectedS3WorkloadRelatedData[mark].Len())
+	}
+}
+
+// recordIntegrityCheck collects the outcome of the read-back of an acknowledged
+// object for the current mark and appends it to the store.
+func (p *Probe) recordIntegrityCheck(check *IntegrityCheck) {
+	p.S3WorkloadMtx.Lock()
+	defer p.S3WorkloadMtx.Unlock()
+
+	if check.Reason != "" {
+		Logger.Errorf("INTEGRITY - object:%s %s", check.Object, check.Reason)
+	}
+	p.CollectedS3WorkloadIntegrity.Record(p.CurrentMark, check)
+	p.Store.Append(&StoreRecord{Kind: StoreRecordIntegrity,
+		Mark:      p.CurrentMark,
+		Integrity: check})
+}
+
 func (p *Probe) RunS3ClientWorkload_SendObject() {
 	Logger.Infof("workload started")
 
@@ -379,28 +650,44 @@ func (p *Probe) RunS3ClientWorkload_SendObject() {
 
 	bucketName := p.CurrentS3WorkloadCfg.FuncArgs["bn"]
 	objName := p.CurrentS3WorkloadCfg.FuncArgs["on"]
-	payload := p.CurrentS3WorkloadCfg.FuncArgs["pl"]
+	payloadSpec, err := ParsePayloadSpec(func(key string) string { return p.CurrentS3WorkloadCfg.FuncArgs[key] })
+	if err != nil {
+		Logger.Errorf("ParsePayloadSpec: %s", err.Error())
+		return
+	}
+
+	//with verify=1 every put goes to its own key, read back at the following ticks
+	var verifier *IntegrityVerifier
+	if p.CurrentS3WorkloadCfg.FuncArgs["verify"] == "1" {
+		verifier = NewIntegrityVerifier(p.CurrentS3WorkloadCfg.Client, bucketName, objName)
+	}
 
 out:
 	for {
 		select {
 		case <-ticker.C:
-			start, end, err := SendObject(p.CurrentS3WorkloadCfg.Client, bucketName, objName, payload)
+			payload, size := payloadSpec.NewReader()
+			putName := objName
+			var sum [md5.Size]byte
+			if verifier != nil {
+				for _, check := range verifier.Verify() {
+					p.recordIntegrityCheck(check)
+				}
+				putName = verifier.NextName()
+				if sum, err = payloadMD5(payload); err != nil {
+					Logger.Errorf("payloadMD5: %s", err.Error())
+					verifier = nil
+				}
+			}
+			start, end, retries, err := SendObject(p.CurrentS3WorkloadCfg.Client, bucketName, putName, payload)
 			if err != nil {
 				Logger.Debugf("SendObject: %s", err.Error())
 			}
+			p.recordS3WorkloadEvent(p.CurrentMark, S3OpPutObject, start, end, size, retries, err)
 
-			p.CurrentS3WorkloadId++
-
-			if p.CollectedS3WorkloadRelatedData[p.CurrentMark] == nil {
-				p.CollectedS3WorkloadRelatedData[p.CurrentMark] = treemap.New[int64, S3WorkloadEvent]()
-			}
-
-			s3WorkloadEvent := S3WorkloadEvent{Id: p.CurrentS3WorkloadId, StartTs: start, EndTs: end, Error: err}
-			p.CollectedS3WorkloadRelatedData[p.CurrentMark].Set(start, s3WorkloadEvent)
-
-			if p.CurrentS3WorkloadId%100 == 0 {
-				Logger.Infof("CollectedS3WorkloadRelatedData[%s] %d", p.CurrentMark, p.CollectedS3WorkloadRelatedData[p.CurrentMark].Len())
+			//the content of an object is unknown after a failed put
+			if verifier != nil && err == nil {
+				verifier.Acked(putName, sum)
 			}
 
 		case evt := <-p.S3WorkloadEvtChan:
@@ -412,7 +699,21 @@ out:
 		}
 	}
 
-	Logger.Infof("CollectedS3WorkloadRelatedData[%s] %d", p.CurrentMark, p.CollectedS3WorkloadRelatedData[p.CurrentMark].Len())
+	if verifier != nil {
+		for _, check := range verifier.Verify() {
+			p.recordIntegrityCheck(check)
+		}
+		if verifier.Pending() > 0 {
+			Logger.Warnf("INTEGRITY - %d objects left unverified", verifier.Pending())
+		}
+	}
+
+	p.S3WorkloadMtx.Lock()
+	p.endS3WorkloadErrorBurst()
+	if s3WLEvents, hit := p.CollectedS3WorkloadRelatedData[p.CurrentMark]; hit {
+		Logger.Infof("CollectedS3WorkloadRelatedData[%s] %d", p.CurrentMark, s3WLEvents.Len())
+	}
+	p.S3WorkloadMtx.Unlock()
 }
 
 func (p *Probe) TriggerS3ClientWorkload() (bool, error) {
@@ -435,19 +736,31 @@ func GetSplitDataForSingleRestartRelatedData(restartEvents []RestartEvent, timeU
 	var evtSeriesFrontedUpData []float64
 	var evtSeriesFUpMainDelta []float64
 	for _, evt := range restartEvents {
+		deathType := evt.How
+		if deathType == "" {
+			deathType = evt.Death.Type
+		}
 		evtSeries = append(evtSeries, RestartEntry{Id: evt.Id,
-			RestartDurationToMain:       (evt.StartMain.Ts - evt.Death.Ts) / timeUnit,
-			RestartDurationToFrontendUp: (evt.StartFrontendUp.Ts - evt.Death.Ts) / timeUnit,
-			FUpMainDelta:                (evt.StartFrontendUp.Ts - evt.StartMain.Ts) / timeUnit})
-
-		evtSeriesMainData = append(evtSeriesMainData, float64(evtSeries[len(evtSeries)-1].RestartDurationToMain))
-		evtSeriesFrontedUpData = append(evtSeriesFrontedUpData, float64(evtSeries[len(evtSeries)-1].RestartDurationToFrontendUp))
-		evtSeriesFUpMainDelta = append(evtSeriesFUpMainDelta, float64(evtSeries[len(evtSeries)-1].FUpMainDelta))
+			RunId:                       evt.RunId,
+			DeathTs:                     evt.Death.Ts,
+			Node:                        evt.Node,
+			DeathType:                   deathType,
+			RestartDurationToMain:       float64(evt.StartMain.Ts-evt.Death.Ts) / float64(timeUnit),
+			RestartDurationToFrontendUp: float64(evt.StartFrontendUp.Ts-evt.Death.Ts) / float64(timeUnit),
+			FUpMainDelta:                float64(evt.StartFrontendUp.Ts-evt.StartMain.Ts) / float64(timeUnit)})
+
+		evtSeriesMainData = append(evtSeriesMainData, evtSeries[len(evtSeries)-1].RestartDurationToMain)
+		evtSeriesFrontedUpData = append(evtSeriesFrontedUpData, evtSeries[len(evtSeries)-1].RestartDurationToFrontendUp)
+		evtSeriesFUpMainDelta = append(evtSeriesFUpMainDelta, evtSeries[len(evtSeries)-1].FUpMainDelta)
 	}
 	return evtSeries, evtSeriesMainData, evtSeriesFrontedUpData, evtSeriesFUpMainDelta
 }
 
 func (p *Probe) ComputeRestartStats(sts *Stats, markPar string, timeUnit string, dumpAllData bool) *Stats {
+	if len(sts.Percentiles) == 0 {
+		sts.Percentiles = DefaultPercentiles
+	}
+
 	for mark, restartEvents := range Prb.CollectedRestartRelatedData {
 
 		if markPar != "all" && markPar != mark {
@@ -459,92 +772,29 @@ func (p *Probe) ComputeRestartStats(sts *Stats, markPar string, timeUnit string,
 			evtSeriesFrontedUpData,
 			evtSeriesFUpMainDelta := GetSplitDataForSingleRestartRelatedData(restartEvents, StrTimeUnit2TimeUnit[timeUnit])
 
-		sts.SeriesRestart = append(sts.SeriesRestart, SeriesRestartEntry{Mark: mark})
-		lastSeries := &sts.SeriesRestart[len(sts.SeriesRestart)-1]
-
-		if val, err := stats.Min(evtSeriesMainData); err == nil {
-			lastSeries.MinMain = int64(val)
-		}
-
-		if val, err := stats.Max(evtSeriesMainData); err == nil {
-			lastSeries.MaxMain = int64(val)
-		}
-
-		if val, err := stats.Mean(evtSeriesMainData); err == nil {
-			lastSeries.MeanMain = int64(val)
-		}
-
-		if val, err := stats.Percentile(evtSeriesMainData, 99); err == nil {
-			lastSeries.Perc99Main = int64(val)
-		}
-
-		if val, err := stats.Percentile(evtSeriesMainData, 95); err == nil {
-			lastSeries.Perc95Main = int64(val)
-		}
-
-		if val, err := stats.PercentileNearestRank(evtSeriesMainData, 99); err == nil {
-			lastSeries.PercNR99Main = int64(val)
-		}
-
-		if val, err := stats.PercentileNearestRank(evtSeriesMainData, 95); err == nil {
-			lastSeries.PercNR95Main = int64(val)
-		}
-
-		if val, err := stats.Min(evtSeriesFrontedUpData); err == nil {
-			lastSeries.MinFrontUp = int64(val)
-		}
-
-		if val, err := stats.Max(evtSeriesFrontedUpData); err == nil {
-			lastSeries.MaxFrontUp = int64(val)
-		}
-
-		if val, err := stats.Mean(evtSeriesFrontedUpData); err == nil {
-			lastSeries.MeanFrontUp = int64(val)
-		}
-
-		if val, err := stats.Percentile(evtSeriesFrontedUpData, 99); err == nil {
-			lastSeries.Perc99FrontUp = int64(val)
-		}
-
-		if val, err := stats.Percentile(evtSeriesFrontedUpData, 95); err == nil {
-			lastSeries.Perc95FrontUp = int64(val)
-		}
-
-		if val, err := stats.PercentileNearestRank(evtSeriesFrontedUpData, 99); err == nil {
-			lastSeries.PercNR99FrontUp = int64(val)
+		if s3WLSeries, _, hit := Prb.s3WorkloadEntries(mark, restartEvents, StrTimeUnit2TimeUnit[timeUnit]); hit {
+			fillRestartEntriesWithS3WorkloadStats(evtSeries, s3WLSeries)
 		}
 
-		if val, err := stats.PercentileNearestRank(evtSeriesFrontedUpData, 95); err == nil {
-			lastSeries.PercNR95FrontUp = int64(val)
-		}
-
-		if val, err := stats.Min(evtSeriesFUpMainDelta); err == nil {
-			lastSeries.MinFUpMainD = int64(val)
-		}
-
-		if val, err := stats.Max(evtSeriesFUpMainDelta); err == nil {
-			lastSeries.MaxFUpMainD = int64(val)
-		}
-
-		if val, err := stats.Mean(evtSeriesFUpMainDelta); err == nil {
-			lastSeries.MeanFUpMainD = int64(val)
-		}
-
-		if val, err := stats.Percentile(evtSeriesFUpMainDelta, 99); err == nil {
-			lastSeries.Perc99FUpMainD = int64(val)
-		}
+		sts.SeriesRestart = append(sts.SeriesRestart, SeriesRestartEntry{Mark: mark})
+		lastSeries := &sts.SeriesRestart[len(sts.SeriesRestart)-1]
 
-		if val, err := stats.Percentile(evtSeriesFUpMainDelta, 95); err == nil {
-			lastSeries.Perc95FUpMainD = int64(val)
-		}
+		lastSeries.ToMain = ComputeDistSummary(evtSeriesMainData, sts.Percentiles)
+		lastSeries.ToFrontendUp = ComputeDistSummary(evtSeriesFrontedUpData, sts.Percentiles)
+		lastSeries.FUpMainDelta = ComputeDistSummary(evtSeriesFUpMainDelta, sts.Percentiles)
 
-		if val, err := stats.PercentileNearestRank(evtSeriesFUpMainDelta, 99); err == nil {
-			lastSeries.PercNR99FUpMainD = int64(val)
-		}
+		ComputeDistSummaryCI(&lastSeries.ToMain, evtSeriesMainData, sts.Percentiles, sts.CI)
+		ComputeDistSummaryCI(&lastSeries.ToFrontendUp, evtSeriesFrontedUpData, sts.Percentiles, sts.CI)
+		ComputeDistSummaryCI(&lastSeries.FUpMainDelta, evtSeriesFUpMainDelta, sts.Percentiles, sts.CI)
 
-		if val, err := stats.PercentileNearestRank(evtSeriesFUpMainDelta, 95); err == nil {
-			lastSeries.PercNR95FUpMainD = int64(val)
-		}
+		ComputeRestartOutliers(lastSeries,
+			evtSeries,
+			restartEvents,
+			evtSeriesMainData,
+			evtSeriesFrontedUpData,
+			evtSeriesFUpMainDelta,
+			sts.Percentiles,
+			&sts.Outliers)
 
 		if dumpAllData {
 			lastSeries.Data = evtSeries
@@ -553,12 +803,12 @@ func (p *Probe) ComputeRestartStats(sts *Stats, markPar string, timeUnit string,
 	return sts
 }
 
-func (p *Probe) SaveStats(genTS string, stats Stats) (string, error) {
+func (p *Probe) SaveStats(genTS string, name string, stats Stats) (string, error) {
 	if resultFile, err := json.MarshalIndent(stats, "", " "); err != nil {
 		Logger.Errorf("json.MarshalIndent:%s", err.Error())
 		return "", err
 	} else {
-		fName := genTS + "_" + p.CurrentMark + "_stats" + ".json"
+		fName := genTS + "_" + name + "_stats" + ".json"
 		if err := os.WriteFile(fName, resultFile, 0644); err != nil {
 			Logger.Errorf("os.WriteFile:%s", err.Error())
 			return "", err
@@ -575,62 +825,102 @@ func unwrapErrorStr(e error) string {
 	}
 }
 
-func GetSplitDataForSingleS3WorkloadRelatedData(restartEvents *treemap.TreeMap[int64, S3WorkloadEvent], timeUnit int64) ([]S3WorkloadEntry, []float64) {
+// s3WorkloadEntries returns the workload entries of the mark, correlated with
+// the restart events, and their RTT; they are copied holding S3WorkloadMtx as
+// the workload keeps collecting events. hit is false without raw events.
+func (p *Probe) s3WorkloadEntries(mark string, restartEvents []RestartEvent, timeUnit int64) ([]S3WorkloadEntry, []float64, bool) {
+	p.S3WorkloadMtx.Lock()
+	defer p.S3WorkloadMtx.Unlock()
+
+	s3WLEvents, hit := p.CollectedS3WorkloadRelatedData[mark]
+	if !hit {
+		return nil, nil, false
+	}
+	evtSeries, evtSeriesRTTData := GetSplitDataForSingleS3WorkloadRelatedData(s3WLEvents, restartEvents, timeUnit)
+	return evtSeries, evtSeriesRTTData, true
+}
+
+func GetSplitDataForSingleS3WorkloadRelatedData(s3WLEvents *treemap.TreeMap[int64, S3WorkloadEvent], restartEvents []RestartEvent, timeUnit int64) ([]S3WorkloadEntry, []float64) {
 	var evtSeries []S3WorkloadEntry
 	var evtSeriesRTTData []float64
-	for it := restartEvents.Iterator(); it.Valid(); it.Next() {
+	for it := s3WLEvents.Iterator(); it.Valid(); it.Next() {
 		val := it.Value()
 		evtSeries = append(evtSeries, S3WorkloadEntry{Id: val.Id,
-			Start:   val.StartTs,
-			End:     val.EndTs,
-			RTT:     (float64(val.EndTs) - float64(val.StartTs)) / float64(timeUnit),
-			ErrDesc: unwrapErrorStr(val.Error)})
+			RunId:      val.RunId,
+			Op:         val.Op,
+			Start:      val.StartTs,
+			End:        val.EndTs,
+			Size:       val.Size,
+			RTT:        (float64(val.EndTs) - float64(val.StartTs)) / float64(timeUnit),
+			Retries:    val.Retries,
+			ErrCode:    val.ErrCode,
+			HTTPStatus: val.HTTPStatus,
+			ErrCat:     val.ErrCat,
+			ErrDesc:    unwrapErrorStr(val.Error)})
 
 		evtSeriesRTTData = append(evtSeriesRTTData, evtSeries[len(evtSeries)-1].RTT)
 	}
+	CorrelateS3WorkloadEntries(restartEvents, evtSeries)
 	return evtSeries, evtSeriesRTTData
 }
 
 func (p *Probe) ComputeS3WorkloadStats(sts *Stats, markPar string, timeUnit string, dumpAllData bool) *Stats {
-	for mark, s3WLEvents := range Prb.CollectedS3WorkloadRelatedData {
+	if len(sts.Percentiles) == 0 {
+		sts.Percentiles = DefaultPercentiles
+	}
+
+	Prb.S3WorkloadMtx.Lock()
+	defer Prb.S3WorkloadMtx.Unlock()
+
+	for mark, markHists := range Prb.CollectedS3WorkloadHistograms {
 
 		if markPar != "all" && markPar != mark {
 			continue
 		}
 
-		evtSeries,
-			evtSeriesRTTData := GetSplitDataForSingleS3WorkloadRelatedData(s3WLEvents, StrTimeUnit2TimeUnit[timeUnit])
-
 		sts.SeriesS3Workload = append(sts.SeriesS3Workload, SeriesS3WorkloadEntry{Mark: mark})
 		lastSeries := &sts.SeriesS3Workload[len(sts.SeriesS3Workload)-1]
 
-		if val, err := stats.Min(evtSeriesRTTData); err == nil {
-			lastSeries.MinRTT = int64(val)
-		}
-
-		if val, err := stats.Max(evtSeriesRTTData); err == nil {
-			lastSeries.MaxRTT = int64(val)
-		}
-
-		if val, err := stats.Mean(evtSeriesRTTData); err == nil {
-			lastSeries.MeanRTT = int64(val)
+		lastSeries.RTTByOp = make(map[string]DistSummary)
+		for op, hist := range markHists {
+			lastSeries.RTTByOp[op] = ComputeDistSummaryFromHistogram(hist, sts.Percentiles, StrTimeUnit2TimeUnit[timeUnit])
+		}
+
+		s3WLEvents, hit := Prb.CollectedS3WorkloadRelatedData[mark]
+		if !hit {
+			//raw events not kept, only the histograms and the error counts are available
+			lastSeries.RTT = ComputeDistSummaryFromHistogram(Prb.CollectedS3WorkloadHistograms.Merged([]string{mark}, ""),
+				sts.Percentiles,
+				StrTimeUnit2TimeUnit[timeUnit])
+			lastSeries.TotalCount = lastSeries.RTT.Count
+			lastSeries.ErrCatCount = make(map[string]uint)
+			for errCat, count := range Prb.CollectedS3WorkloadErrors[mark] {
+				lastSeries.ErrCount += count
+				lastSeries.ErrCatCount[errCat] = count
+			}
+			if lastSeries.TotalCount > 0 {
+				lastSeries.Availability = 100 * float64(lastSeries.TotalCount-lastSeries.ErrCount) / float64(lastSeries.TotalCount)
+			}
+			lastSeries.setIntegrity(Prb.CollectedS3WorkloadIntegrity[mark])
+			continue
 		}
+		lastSeries.RawEvents = true
+		lastSeries.setIntegrity(Prb.CollectedS3WorkloadIntegrity[mark])
 
-		if val, err := stats.Percentile(evtSeriesRTTData, 99); err == nil {
-			lastSeries.Perc99RTT = int64(val)
-		}
+		evtSeries,
+			evtSeriesRTTData := GetSplitDataForSingleS3WorkloadRelatedData(s3WLEvents, Prb.CollectedRestartRelatedData[mark], StrTimeUnit2TimeUnit[timeUnit])
 
-		if val, err := stats.Percentile(evtSeriesRTTData, 95); err == nil {
-			lastSeries.Perc95RTT = int64(val)
-		}
+		lastSeries.RTT = ComputeDistSummary(evtSeriesRTTData, sts.Percentiles)
 
-		if val, err := stats.PercentileNearestRank(evtSeriesRTTData, 99); err == nil {
-			lastSeries.PercNR99RTT = int64(val)
+		lastSeries.ErrCatCount = make(map[string]uint)
+		for _, it := range evtSeries {
+			if it.ErrCat != ErrCatNone {
+				lastSeries.ErrCount++
+				lastSeries.ErrCatCount[it.ErrCat]++
+			}
 		}
 
-		if val, err := stats.PercentileNearestRank(evtSeriesRTTData, 95); err == nil {
-			lastSeries.PercNR95RTT = int64(val)
-		}
+		ComputeAvailability(lastSeries, evtSeries, StrTimeUnit2TimeUnit[timeUnit])
 
 		if dumpAllData {
 			lastSeries.Data = evtSeries
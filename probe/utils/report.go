@@ -0,0 +1,349 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/base64"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportCampaign is the configuration of the campaign a report is about.
+type ReportCampaign struct {
+	Mark           string
+	DeathType      string
+	GracePeriod    uint
+	LieDownPeriod  uint
+	SettlePeriod   uint
+	Nodes          []string
+	Hooks          []Hook
+	SLOs           []SLO
+	S3WorkloadFunc string
+	S3WorkloadFreq uint
+	S3WorkloadArgs map[string]string
+}
+
+// ReportPlot is a plot embedded in the report,
+// inlined as svg or, when there is no svg, as a png data URI.
+type ReportPlot struct {
+	Name string
+	SVG  template.HTML
+	PNG  template.URL
+}
+
+// ReportRestart is a row of the per-restart table with the requests
+// of the workload attributed to the restart and, for outliers, the K8s events.
+type ReportRestart struct {
+	RestartEntry
+	S3Workload []S3WorkloadEntry
+	K8sEvents  []K8sEventEntry
+}
+
+// ReportSeries is a restart series with the workload series of the same mark.
+type ReportSeries struct {
+	Restart    *SeriesRestartEntry
+	S3Workload *SeriesS3WorkloadEntry
+	Restarts   []ReportRestart
+}
+
+type Report struct {
+	Title       string
+	GeneratedAt string
+	Campaign    *ReportCampaign
+	Stats       *Stats
+	Series      []ReportSeries
+	Plots       []ReportPlot
+}
+
+var reportFuncs = template.FuncMap{
+	"pkey": PercentileKey,
+	"dict": func(kv ...interface{}) map[string]interface{} {
+		dict := make(map[string]interface{})
+		for i := 0; i+1 < len(kv); i += 2 {
+			dict[kv[i].(string)] = kv[i+1]
+		}
+		return dict
+	},
+	"num": func(val float64) string {
+		return strconv.FormatFloat(val, 'f', 3, 64)
+	},
+	"ts": func(ts int64) string {
+		if ts == 0 {
+			return "-"
+		}
+		return time.Unix(0, ts).UTC().Format("2006-01-02 15:04:05.000")
+	},
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.6em; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+tr.outlier { background: #fff3cd; }
+.pass { color: #1a7f37; font-weight: bold; }
+.fail { color: #cf222e; font-weight: bold; }
+.plot svg, .plot img { max-width: 100%; height: auto; }
+details table { margin: 0.5em 0; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated at {{.GeneratedAt}}, durations in {{.Stats.TimeUnit}}.</p>
+
+{{with .Campaign}}
+<h2>Campaign</h2>
+<table>
+<tr><td>mark</td><td>{{.Mark}}</td></tr>
+<tr><td>death type</td><td>{{.DeathType}}</td></tr>
+<tr><td>grace (ms)</td><td>{{.GracePeriod}}</td></tr>
+<tr><td>lie down (ms)</td><td>{{.LieDownPeriod}}</td></tr>
+<tr><td>settle (ms)</td><td>{{.SettlePeriod}}</td></tr>
+{{if .Nodes}}<tr><td>nodes</td><td>{{range .Nodes}}{{.}} {{end}}</td></tr>{{end}}
+{{range .Hooks}}<tr><td>hook</td><td>{{.Phase}}:{{.Action}} {{range $k, $v := .Args}}{{$k}}={{$v}} {{end}}{{if .Every}}every {{.Every}}{{end}}</td></tr>{{end}}
+{{if .S3WorkloadFunc}}<tr><td>S3 workload</td><td>{{.S3WorkloadFunc}} every {{.S3WorkloadFreq}} ms {{range $k, $v := .S3WorkloadArgs}}{{$k}}={{$v}} {{end}}</td></tr>{{end}}
+{{range .SLOs}}<tr><td>SLO</td><td>{{.Expr}}</td></tr>{{end}}
+</table>
+{{end}}
+
+<h2>Statistics configuration</h2>
+<table>
+<tr><td>percentiles</td><td>{{range .Stats.Percentiles}}{{pkey .}} {{end}}</td></tr>
+<tr><td>CI</td><td>{{.Stats.CI.Level}}%, {{.Stats.CI.Resamples}} resamples</td></tr>
+<tr><td>outliers</td><td>{{.Stats.Outliers.Method}}{{if .Stats.Outliers.K}}, k {{.Stats.Outliers.K}}{{end}}</td></tr>
+</table>
+
+{{with .Stats.SLO}}
+<h2>SLO verdict: <span class="{{.Status}}">{{.Status}}</span></h2>
+<table>
+<tr><th>SLO</th><th>mark</th><th>value</th><th>verdict</th></tr>
+{{range .Results}}<tr><td>{{.SLO}}</td><td>{{.Mark}}</td><td>{{num .Value}}</td><td>{{if .Error}}{{.Error}}{{else if .Pass}}<span class="pass">pass</span>{{else}}<span class="fail">fail</span>{{end}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+{{$percentiles := .Stats.Percentiles}}
+{{range .Series}}
+{{with .Restart}}
+<h2>Restarts: {{.Mark}}</h2>
+<table>
+<tr><th>metric</th><th>count</th><th>min</th><th>mean</th><th>mean CI</th><th>median</th><th>max</th><th>std dev</th>{{range $percentiles}}<th>{{pkey .}}</th>{{end}}</tr>
+{{template "summary" (dict "name" "to main" "sum" .ToMain "percentiles" $percentiles)}}
+{{template "summary" (dict "name" "to frontend up" "sum" .ToFrontendUp "percentiles" $percentiles)}}
+{{template "summary" (dict "name" "frontend up - main" "sum" .FUpMainDelta "percentiles" $percentiles)}}
+{{if .Outliers}}
+{{template "summary" (dict "name" "to main, no outliers" "sum" .ToMainNoOutliers "percentiles" $percentiles)}}
+{{template "summary" (dict "name" "to frontend up, no outliers" "sum" .ToFrontendUpNoOutliers "percentiles" $percentiles)}}
+{{end}}
+</table>
+{{end}}
+{{with .S3Workload}}
+<h2>S3 workload: {{.Mark}}</h2>
+<table>
+<tr><td>requests</td><td>{{.TotalCount}}</td></tr>
+<tr><td>errors</td><td>{{.ErrCount}}{{range $cat, $count := .ErrCatCount}} {{$cat}}:{{$count}}{{end}}</td></tr>
+<tr><td>availability (%)</td><td>{{num .Availability}}</td></tr>
+{{if .IntegrityChecks}}
+<tr><td>integrity failures</td><td>{{.IntegrityFailures}} / {{.IntegrityChecks}} checks</td></tr>
+{{end}}
+{{if .RawEvents}}
+<tr><td>time availability (%)</td><td>{{num .TimeAvailability}}</td></tr>
+<tr><td>unavailability</td><td>{{num .Unavailability}}</td></tr>
+<tr><td>first failure</td><td>{{ts .FirstFailure}}</td></tr>
+<tr><td>last failure</td><td>{{ts .LastFailure}}</td></tr>
+{{else}}
+<tr><td>time availability (%)</td><td>not measured (raw events not kept)</td></tr>
+{{end}}
+</table>
+<table>
+<tr><th>metric</th><th>count</th><th>min</th><th>mean</th><th>mean CI</th><th>median</th><th>max</th><th>std dev</th>{{range $percentiles}}<th>{{pkey .}}</th>{{end}}</tr>
+{{template "summary" (dict "name" "RTT" "sum" .RTT "percentiles" $percentiles)}}
+{{range $op, $sum := .RTTByOp}}{{template "summary" (dict "name" (print "RTT " $op) "sum" $sum "percentiles" $percentiles)}}{{end}}
+</table>
+{{end}}
+{{if .Restarts}}
+<h3>Restarts</h3>
+<table>
+<tr><th>id</th><th>death</th><th>node</th><th>death type</th><th>to main</th><th>to frontend up</th><th>frontend up - main</th><th>requests</th><th>errors</th><th>max RTT</th><th>details</th></tr>
+{{range .Restarts}}
+<tr{{if .Outlier}} class="outlier"{{end}}><td>{{.Id}}</td><td>{{ts .DeathTs}}</td><td>{{.Node}}</td><td>{{.DeathType}}</td><td>{{num .RestartDurationToMain}}</td><td>{{num .RestartDurationToFrontendUp}}</td><td>{{num .FUpMainDelta}}</td><td>{{.WLCount}}</td><td>{{.WLErrCount}}</td><td>{{num .WLMaxRTT}}</td>
+<td>{{if or .S3Workload .K8sEvents}}<details><summary>requests: {{len .S3Workload}}{{if .K8sEvents}}, K8s events: {{len .K8sEvents}}{{end}}</summary>
+{{if .S3Workload}}<table>
+<tr><th>wl id</th><th>start</th><th>RTT</th><th>retries</th><th>HTTP status</th><th>error</th></tr>
+{{range .S3Workload}}<tr><td>{{.Id}}</td><td>{{ts .Start}}</td><td>{{num .RTT}}</td><td>{{.Retries}}</td><td>{{.HTTPStatus}}</td><td>{{.ErrCat}} {{.ErrCode}}</td></tr>
+{{end}}</table>{{end}}
+{{if .K8sEvents}}<table>
+<tr><th>ts</th><th>type</th><th>reason</th><th>object</th><th>message</th></tr>
+{{range .K8sEvents}}<tr><td>{{ts .Ts}}</td><td>{{.Type}}</td><td>{{.Reason}}</td><td>{{.Object}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>{{end}}
+</details>{{end}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{end}}
+
+{{if .Plots}}
+<h2>Plots</h2>
+{{range .Plots}}<div class="plot"><h3>{{.Name}}</h3>{{if .SVG}}{{.SVG}}{{else}}<img src="{{.PNG}}" alt="{{.Name}}">{{end}}</div>
+{{end}}
+{{end}}
+</body>
+</html>
+{{define "summary"}}<tr><td>{{.name}}</td><td>{{.sum.Count}}</td><td>{{num .sum.Min}}</td><td>{{num .sum.Mean}}</td><td>{{with .sum.MeanCI}}{{num .Low}} - {{num .High}}{{else}}-{{end}}</td><td>{{num .sum.Median}}</td><td>{{num .sum.Max}}</td><td>{{num .sum.StdDev}}</td>{{$sum := .sum}}{{range .percentiles}}<td>{{num (index $sum.Percentiles (pkey .))}}</td>{{end}}</tr>{{end}}
+`))
+
+// reportCampaign returns the parameters of the campaign in progress when the
+// report is about it, i.e. its mark or, for a sweep, its base or step marks.
+func (p *Probe) reportCampaign(name string) *ReportCampaign {
+	if p.CurrentMark == "" {
+		return nil
+	}
+	if name != p.CurrentMark && (p.CurrentSweep == nil ||
+		(name != p.CurrentSweep.BaseMark && !containsString(p.CurrentSweep.StepMarks, name))) {
+		return nil
+	}
+	campaign := ReportCampaign{Mark: p.CurrentMark,
+		DeathType:      p.CurrentDeathType,
+		GracePeriod:    p.CurrentGracePeriod,
+		LieDownPeriod:  p.CurrentLieDownPeriod,
+		SettlePeriod:   p.CurrentSettlePeriod,
+		Hooks:          p.CurrentHooks,
+		SLOs:           p.CurrentSLOs,
+		S3WorkloadFunc: p.CurrentS3WorkloadCfg.FuncName,
+		S3WorkloadFreq: p.CurrentS3WorkloadCfg.Frequency,
+		S3WorkloadArgs: p.CurrentS3WorkloadCfg.FuncArgs}
+	if p.CurrentNodeNameList != nil {
+		campaign.Nodes = *p.CurrentNodeNameList
+	} else if p.CurrentSelectedNode != "" {
+		campaign.Nodes = []string{p.CurrentSelectedNode}
+	}
+	return &campaign
+}
+
+// readSVGPlot returns the svg element of a plot file, ready to be inlined.
+func readSVGPlot(fName string) (template.HTML, error) {
+	data, err := os.ReadFile(fName)
+	if err != nil {
+		return "", err
+	}
+	svg := string(data)
+	if idx := strings.Index(svg, "<svg"); idx > 0 {
+		svg = svg[idx:]
+	}
+	return template.HTML(svg), nil
+}
+
+// readPNGPlot returns a plot file as a png data URI.
+func readPNGPlot(fName string) (template.URL, error) {
+	data, err := os.ReadFile(fName)
+	if err != nil {
+		return "", err
+	}
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// GenerateHTMLReport writes a self-contained HTML report with the campaign
+// configuration, the SLO verdict, the summaries, the per-restart drill-down
+// and the plots among fNames inlined, as svg if any else as png.
+func (p *Probe) GenerateHTMLReport(genTS string, name string, stats *Stats, fNames []string) (string, error) {
+	report := Report{Title: "s3gw probe report: " + name,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Campaign:    p.reportCampaign(name),
+		Stats:       stats}
+	if name == "" {
+		report.Title = "s3gw probe report"
+	}
+
+	s3WLSeries := make(map[string]*SeriesS3WorkloadEntry)
+	for i := range stats.SeriesS3Workload {
+		s3WLSeries[stats.SeriesS3Workload[i].Mark] = &stats.SeriesS3Workload[i]
+	}
+
+	for i := range stats.SeriesRestart {
+		restartSeries := &stats.SeriesRestart[i]
+		series := ReportSeries{Restart: restartSeries, S3Workload: s3WLSeries[restartSeries.Mark]}
+		delete(s3WLSeries, restartSeries.Mark)
+
+		outliers := make(map[int][]K8sEventEntry)
+		for _, it := range restartSeries.Outliers {
+			outliers[it.Id] = it.K8sEvents
+		}
+		for _, it := range restartSeries.Data {
+			restart := ReportRestart{RestartEntry: it, K8sEvents: outliers[it.Id]}
+			if series.S3Workload != nil {
+				for _, wl := range series.S3Workload.Data {
+					if wl.RestartId == it.Id {
+						restart.S3Workload = append(restart.S3Workload, wl)
+					}
+				}
+			}
+			series.Restarts = append(series.Restarts, restart)
+		}
+		report.Series = append(report.Series, series)
+	}
+	for i := range stats.SeriesS3Workload {
+		if _, hit := s3WLSeries[stats.SeriesS3Workload[i].Mark]; hit {
+			report.Series = append(report.Series, ReportSeries{S3Workload: &stats.SeriesS3Workload[i]})
+		}
+	}
+
+	plotExts := make(map[string][]string)
+	plotNames := []string{}
+	for _, fName := range fNames {
+		ext := filepath.Ext(fName)
+		if ext != ".svg" && ext != ".png" {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(fName, genTS+"_"), ext)
+		if _, hit := plotExts[name]; !hit {
+			plotNames = append(plotNames, name)
+		}
+		plotExts[name] = append(plotExts[name], ext)
+	}
+	for _, name := range plotNames {
+		plot := ReportPlot{Name: name}
+		var err error
+		if containsString(plotExts[name], ".svg") {
+			plot.SVG, err = readSVGPlot(genTS + "_" + name + ".svg")
+		} else {
+			plot.PNG, err = readPNGPlot(genTS + "_" + name + ".png")
+		}
+		if err != nil {
+			Logger.Errorf("GenerateHTMLReport: reading plot:%s", err.Error())
+			continue
+		}
+		report.Plots = append(report.Plots, plot)
+	}
+
+	fName := genTS + "_" + name + "_report" + ".html"
+	f, err := os.Create(fName)
+	if err != nil {
+		Logger.Errorf("GenerateHTMLReport: os.Create:%s", err.Error())
+		return "", err
+	}
+	defer f.Close()
+
+	if err := reportTemplate.Execute(f, &report); err != nil {
+		Logger.Errorf("GenerateHTMLReport: Execute:%s", err.Error())
+		return "", err
+	}
+	return fName, nil
+}
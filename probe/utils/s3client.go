@@ -12,12 +12,16 @@
 package utils
 
 import (
-	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 )
@@ -34,15 +38,35 @@ func CreateBucket(client *s3.S3, bucketName string) error {
 	return err
 }
 
-func SendObject(client *s3.S3, bucketName string, objName string, payload string) (int64, int64, error) {
-	start := time.Now().UnixNano()
-	_, err := client.PutObject(&s3.PutObjectInput{
+// SendObject puts an object and returns the start/end timestamps of the
+// operation, the number of retries performed by the SDK and the final error.
+func SendObject(client *s3.S3, bucketName string, objName string, payload io.ReadSeeker) (int64, int64, int, error) {
+	req, _ := client.PutObjectRequest(&s3.PutObjectInput{
 		Bucket: &bucketName,
 		Key:    &objName,
-		Body:   bytes.NewReader([]byte(payload))})
+		Body:   payload})
 
+	start := time.Now().UnixNano()
+	err := req.Send()
 	end := time.Now().UnixNano()
-	return start, end, err
+	return start, end, req.RetryCount, err
+}
+
+// GetErrorCode returns the S3/SDK error code and the HTTP status code
+// (0 when no response was received) carried by an error.
+func GetErrorCode(err error) (string, int) {
+	if err == nil {
+		return "", 0
+	}
+	var reqFailure awserr.RequestFailure
+	if errors.As(err, &reqFailure) {
+		return reqFailure.Code(), reqFailure.StatusCode()
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code(), 0
+	}
+	return "", 0
 }
 
 func EraseObject(client *s3.S3, bucketName string, objName string) error {
@@ -68,33 +92,35 @@ func SendObjectFromFile(client *s3.S3, bucketName string, fileName string) error
 	return nil
 }
 
-func SendStatsArtifactsToS3(client *s3.S3, bucketName string, fNames []string) {
-	for _, fName := range fNames {
-		SendObjectFromFile(client, bucketName, fName)
+// ListObjects returns the objects of the bucket whose key starts with prefix.
+func ListObjects(client *s3.S3, bucketName string, prefix string) ([]*s3.Object, error) {
+	var objects []*s3.Object
+	err := client.ListObjectsV2Pages(&s3.ListObjectsV2Input{Bucket: &bucketName, Prefix: &prefix},
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			objects = append(objects, page.Contents...)
+			return true
+		})
+	if err != nil {
+		Logger.Errorf("ListObjectsV2Pages:%s", err.Error())
+		return nil, err
 	}
+	return objects, nil
 }
 
-func FillWithObjects(client *s3.S3, bucketName string, objBaseName string, payload string, objCount uint64, addTS bool) error {
-	CreateBucket(client, bucketName)
-	for i := uint64(0); i < objCount; i++ {
-		var objName string
-		if addTS {
-			objName = objBaseName + "_" + strconv.FormatUint(i, 10) + "_" + strconv.Itoa(int(time.Now().Unix()))
-		} else {
-			objName = objBaseName + "_" + strconv.FormatUint(i, 10)
-		}
-
-		SendObject(client, bucketName, objName, payload)
+func GetObject(client *s3.S3, bucketName string, objName string) ([]byte, error) {
+	out, err := client.GetObject(&s3.GetObjectInput{Bucket: &bucketName, Key: &objName})
+	if err != nil {
+		Logger.Errorf("GetObject:%s", err.Error())
+		return nil, err
 	}
-	return nil
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
 }
 
-func EraseObjects(client *s3.S3, bucketName string, objBaseName string, objCount uint64) error {
-	for i := uint64(0); i < objCount; i++ {
-		objName := objBaseName + "_" + strconv.FormatUint(i, 10)
-		EraseObject(client, bucketName, objName)
+func SendStatsArtifactsToS3(client *s3.S3, bucketName string, fNames []string) {
+	for _, fName := range fNames {
+		SendObjectFromFile(client, bucketName, fName)
 	}
-	return nil
 }
 
 func InitS3Client_SaveData() *s3.S3 {
@@ -133,6 +159,87 @@ func InitS3Client_S3GW() *s3.S3 {
 	return s3Client
 }
 
+// S3ClientPolicy holds the timeouts and the retry policy applied
+// to the HTTP requests issued by an S3 client.
+type S3ClientPolicy struct {
+	ConnectTimeout uint //msec, 0 means no timeout
+	RequestTimeout uint //msec, 0 means no timeout
+	MaxRetries     int  //-1 means SDK default
+}
+
+func (pol *S3ClientPolicy) Reset() {
+	pol.ConnectTimeout = 0
+	pol.RequestTimeout = 0
+	pol.MaxRetries = aws.UseServiceDefaultRetries
+}
+
+func (pol *S3ClientPolicy) IsDefault() bool {
+	return pol.ConnectTimeout == 0 && pol.RequestTimeout == 0 && pol.MaxRetries == aws.UseServiceDefaultRetries
+}
+
+// ParseS3ClientPolicy parses the connect and request timeouts (msec) and
+// the max retries (non negative); empty values are defaulted.
+func ParseS3ClientPolicy(connTimeout string, reqTimeout string, retries string) (S3ClientPolicy, error) {
+	pol := S3ClientPolicy{}
+	pol.Reset()
+	if connTimeout != "" {
+		val, err := strconv.ParseUint(connTimeout, 0, 32)
+		if err != nil {
+			return pol, err
+		}
+		pol.ConnectTimeout = uint(val)
+	}
+	if reqTimeout != "" {
+		val, err := strconv.ParseUint(reqTimeout, 0, 32)
+		if err != nil {
+			return pol, err
+		}
+		pol.RequestTimeout = uint(val)
+	}
+	if retries != "" {
+		val, err := strconv.ParseInt(retries, 0, 32)
+		if err != nil {
+			return pol, err
+		}
+		if val < 0 {
+			return pol, errors.New("retries must not be negative: " + retries)
+		}
+		pol.MaxRetries = int(val)
+	}
+	return pol, nil
+}
+
+// InitS3Client_WithPolicy creates an S3 client for the given endpoint
+// honoring the timeouts and the retry policy.
+func InitS3Client_WithPolicy(endpoint string, forcePathStyle bool, policy S3ClientPolicy) *s3.S3 {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   time.Duration(policy.ConnectTimeout) * time.Millisecond,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	session, err := session.NewSessionWithOptions(session.Options{
+		Config: aws.Config{
+			S3ForcePathStyle: aws.Bool(forcePathStyle),
+			Endpoint:         aws.String(endpoint),
+			Region:           aws.String("US"),
+			MaxRetries:       aws.Int(policy.MaxRetries),
+			HTTPClient: &http.Client{
+				Transport: transport,
+				Timeout:   time.Duration(policy.RequestTimeout) * time.Millisecond,
+			},
+		},
+	})
+
+	if err != nil {
+		Logger.Errorf("InitS3Client_WithPolicy: Failed to initialize new session:%s", err.Error())
+		return nil
+	}
+
+	s3Client := s3.New(session)
+	return s3Client
+}
+
 func InitS3Client_S3GW_Ingress() *s3.S3 {
 	session, err := session.NewSessionWithOptions(session.Options{
 		Config: aws.Config{
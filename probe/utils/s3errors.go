@@ -0,0 +1,93 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+const (
+	ErrCatNone        = ""
+	ErrCatConnRefused = "conn_refused"
+	ErrCatConnReset   = "conn_reset"
+	ErrCatTimeout     = "timeout"
+	ErrCatDNS         = "dns"
+	ErrCatHTTP4xx     = "http_4xx"
+	ErrCatHTTP5xx     = "http_5xx" //suffixed with the status code, e.g. http_5xx_503
+	ErrCatS3          = "s3"       //suffixed with the S3 error code, e.g. s3_NoSuchBucket
+	ErrCatOther       = "other"
+)
+
+// rootCause walks the chain of SDK errors down to the
+// original error that caused the failure.
+func rootCause(err error) error {
+	for {
+		awsErr, ok := err.(awserr.Error)
+		if !ok || awsErr.OrigErr() == nil {
+			return err
+		}
+		err = awsErr.OrigErr()
+	}
+}
+
+// ClassifyError maps an error returned by an S3 operation to a category.
+func ClassifyError(err error, errCode string, httpStatus int) string {
+	if err == nil {
+		return ErrCatNone
+	}
+
+	if httpStatus >= 500 {
+		return ErrCatHTTP5xx + "_" + strconv.Itoa(httpStatus)
+	}
+
+	if httpStatus >= 400 {
+		if errCode != "" && errCode != strconv.Itoa(httpStatus) {
+			return ErrCatS3 + "_" + errCode
+		}
+		return ErrCatHTTP4xx
+	}
+
+	cause := rootCause(err)
+
+	var dnsErr *net.DNSError
+	if errors.As(cause, &dnsErr) {
+		return ErrCatDNS
+	}
+
+	if errors.Is(cause, syscall.ECONNREFUSED) {
+		return ErrCatConnRefused
+	}
+
+	if errors.Is(cause, syscall.ECONNRESET) || errors.Is(cause, syscall.EPIPE) {
+		return ErrCatConnReset
+	}
+
+	var netErr net.Error
+	if errors.Is(cause, os.ErrDeadlineExceeded) ||
+		(errors.As(cause, &netErr) && netErr.Timeout()) ||
+		errCode == request.ErrCodeResponseTimeout {
+		return ErrCatTimeout
+	}
+
+	if errCode != "" && errCode != request.ErrCodeRequestError {
+		return ErrCatS3 + "_" + errCode
+	}
+
+	return ErrCatOther
+}
@@ -19,9 +19,11 @@ package utils
 import (
 	"image/color"
 	"math"
+	"sort"
 
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
 )
@@ -34,14 +36,26 @@ type S3WorkloadBars struct {
 	//TimeUnit
 	TimeUnit int64
 
+	// WallClock places the bars at the Unix time in seconds of their
+	// start instead of the time elapsed since the first entry
+	WallClock bool
+
 	// ColorOK is the color of bars when the S3WorkloadEntry
 	// has completed successfully
 	ColorOK color.Color
 
 	// ColorErr is the color of bars when the S3WorkloadEntry
-	// has completed with an error
+	// has completed with an error not belonging to any category
 	ColorErr color.Color
 
+	// CategoryColors maps an error category to the color of bars
+	// when the S3WorkloadEntry has completed with that error
+	CategoryColors map[string]color.Color
+
+	// Categories is the sorted list of the error categories
+	// found in the data
+	Categories []string
+
 	// LineStyle is the style used to draw the bars.
 	draw.LineStyle
 }
@@ -49,13 +63,52 @@ type S3WorkloadBars struct {
 // NewBars creates as new bar plotter for
 // the given data.
 func NewVBars(S3WLE *[]S3WorkloadEntry, timeUnit string) (*S3WorkloadBars, error) {
-	return &S3WorkloadBars{
-		S3WLE:     S3WLE,
-		TimeUnit:  StrTimeUnit2TimeUnit[timeUnit],
-		ColorOK:   color.RGBA{R: 0, G: 128, B: 0, A: 255}, // eye is more sensible to green
-		ColorErr:  color.RGBA{R: 196, G: 0, B: 0, A: 255},
-		LineStyle: plotter.DefaultLineStyle,
-	}, nil
+	bars := &S3WorkloadBars{
+		S3WLE:          S3WLE,
+		TimeUnit:       StrTimeUnit2TimeUnit[timeUnit],
+		ColorOK:        color.RGBA{R: 0, G: 128, B: 0, A: 255}, // eye is more sensible to green
+		ColorErr:       color.RGBA{R: 196, G: 0, B: 0, A: 255},
+		CategoryColors: make(map[string]color.Color),
+		LineStyle:      plotter.DefaultLineStyle,
+	}
+
+	for _, it := range *S3WLE {
+		if it.ErrCat != ErrCatNone {
+			bars.CategoryColors[it.ErrCat] = nil
+		}
+	}
+	for category := range bars.CategoryColors {
+		bars.Categories = append(bars.Categories, category)
+	}
+	sort.Strings(bars.Categories)
+
+	// plotutil.Color(1) is green, skip it so that errors never look OK
+	errColorIdxs := []int{0, 2, 3, 4, 5, 6}
+	for i, category := range bars.Categories {
+		bars.CategoryColors[category] = plotutil.Color(errColorIdxs[i%len(errColorIdxs)])
+	}
+
+	return bars, nil
+}
+
+// AddLegend adds an entry for the successful operations
+// and one for each error category to the plot's legend.
+func (bars *S3WorkloadBars) AddLegend(plt *plot.Plot) {
+	lineStyle := bars.LineStyle
+	lineStyle.Color = bars.ColorOK
+	plt.Legend.Add("ok", &plotter.Line{LineStyle: lineStyle})
+
+	for _, category := range bars.Categories {
+		lineStyle.Color = bars.CategoryColors[category]
+		plt.Legend.Add(category, &plotter.Line{LineStyle: lineStyle})
+	}
+}
+
+func (bars *S3WorkloadBars) x(it *S3WorkloadEntry) float64 {
+	if bars.WallClock {
+		return float64(it.Start) / Sec
+	}
+	return float64(((it.Start - (*bars.S3WLE)[0].Start) / bars.TimeUnit))
 }
 
 // Plot implements the Plot method of the plot.Plotter interface.
@@ -64,7 +117,9 @@ func (bars *S3WorkloadBars) Plot(c draw.Canvas, plt *plot.Plot) {
 	lineStyle := bars.LineStyle
 
 	for _, it := range *bars.S3WLE {
-		if it.ErrDesc != "" {
+		if catColor, hit := bars.CategoryColors[it.ErrCat]; hit {
+			lineStyle.Color = catColor
+		} else if it.ErrDesc != "" {
 			lineStyle.Color = bars.ColorErr
 		} else {
 			lineStyle.Color = bars.ColorOK
@@ -72,7 +127,7 @@ func (bars *S3WorkloadBars) Plot(c draw.Canvas, plt *plot.Plot) {
 
 		// Transform the data
 		// to the corresponding drawing coordinate.
-		x := trX(float64(((it.Start - (*bars.S3WLE)[0].Start) / bars.TimeUnit)))
+		x := trX(bars.x(&it))
 		y0 := trY(0)
 		y := trY(it.RTT)
 
@@ -90,8 +145,8 @@ func (bars *S3WorkloadBars) DataRange() (xmin, xmax, ymin, ymax float64) {
 	ymin = 0
 	ymax = math.Inf(-1)
 	for _, it := range *bars.S3WLE {
-		xmin = math.Min(xmin, float64(((it.Start - (*bars.S3WLE)[0].Start) / bars.TimeUnit)))
-		xmax = math.Max(xmax, float64(((it.Start - (*bars.S3WLE)[0].Start) / bars.TimeUnit)))
+		xmin = math.Min(xmin, bars.x(&it))
+		xmax = math.Max(xmax, bars.x(&it))
 		ymax = math.Max(ymax, it.RTT)
 	}
 	return
@@ -0,0 +1,252 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SLOStatusNone = "none" //no campaign has been evaluated yet
+	SLOStatusPass = "pass"
+	SLOStatusFail = "fail"
+)
+
+// restart duration metrics and workload metrics an SLO can be set on;
+// the duration metrics require a stat, e.g. to_frontend_up.p95
+var sloRestartMetrics = map[string]func(*SeriesRestartEntry) *DistSummary{
+	"to_main":                func(s *SeriesRestartEntry) *DistSummary { return &s.ToMain },
+	"to_frontend_up":         func(s *SeriesRestartEntry) *DistSummary { return &s.ToFrontendUp },
+	"frontend_up_main_delta": func(s *SeriesRestartEntry) *DistSummary { return &s.FUpMainDelta },
+}
+
+var errNotMeasurable = errors.New("not measurable without the raw workload events")
+var errIntegrityNotVerified = errors.New("not measured, the workload does not verify the objects (verify=1)")
+
+var sloS3WorkloadMetrics = map[string]func(*SeriesS3WorkloadEntry) (float64, error){
+	"availability": func(s *SeriesS3WorkloadEntry) (float64, error) { return s.Availability, nil },
+	"time_availability": func(s *SeriesS3WorkloadEntry) (float64, error) {
+		if !s.RawEvents {
+			return 0, errNotMeasurable
+		}
+		return s.TimeAvailability, nil
+	},
+	"err_count": func(s *SeriesS3WorkloadEntry) (float64, error) { return float64(s.ErrCount), nil },
+	"integrity_failures": func(s *SeriesS3WorkloadEntry) (float64, error) {
+		if s.IntegrityChecks == 0 {
+			return 0, errIntegrityNotVerified
+		}
+		return float64(s.IntegrityFailures), nil
+	},
+}
+
+var sloOps = []string{"<=", ">=", "==", "<", ">"} //longest first
+
+// SLO is a threshold on a metric of a campaign, e.g. to_frontend_up.p95<3s
+type SLO struct {
+	Expr      string  `json:"expr"`
+	Metric    string  `json:"metric"`
+	Stat      string  `json:"stat"`
+	Op        string  `json:"op"`
+	Threshold float64 `json:"threshold"` //durations in ms
+}
+
+type SLOResult struct {
+	SLO   string  `json:"slo"`
+	Mark  string  `json:"mark"`
+	Value float64 `json:"value"`
+	Pass  bool    `json:"pass"`
+	Error string  `json:"error,omitempty"`
+}
+
+// SLOVerdict is the outcome of the evaluation of the SLOs of a campaign.
+type SLOVerdict struct {
+	Status      string      `json:"status"`
+	Mark        string      `json:"mark"`
+	EvaluatedAt int64       `json:"evaluated_at"`
+	Results     []SLOResult `json:"results"`
+}
+
+func isDurationMetric(metric string) bool {
+	_, hit := sloRestartMetrics[metric]
+	return hit || metric == "RTT"
+}
+
+// ParseSLO parses an SLO in the form <metric>[.<stat>]<op><value>[<time unit>],
+// stat is one of mean, median, min, max, std_dev, mad, p<n>; durations are in ms
+// unless a time unit is given.
+func ParseSLO(str string) (*SLO, error) {
+	slo := SLO{Expr: str}
+
+	opIdx := -1
+	for _, op := range sloOps {
+		if idx := strings.Index(str, op); idx > 0 {
+			opIdx = idx
+			slo.Op = op
+			break
+		}
+	}
+	if opIdx < 0 {
+		return nil, errors.New("malformed SLO: " + str)
+	}
+
+	metric := str[:opIdx]
+	value := str[opIdx+len(slo.Op):]
+
+	if tokens := strings.SplitN(metric, ".", 2); len(tokens) == 2 {
+		slo.Metric, slo.Stat = tokens[0], tokens[1]
+	} else {
+		slo.Metric = metric
+	}
+
+	if isDurationMetric(slo.Metric) {
+		if slo.Stat == "" {
+			return nil, errors.New("missing stat for SLO: " + str)
+		}
+		if _, err := sloStatValue(&DistSummary{}, slo.Stat, true); err != nil {
+			return nil, err
+		}
+		unit := int64(MilliS)
+		for _, strUnit := range []string{StrNanoS, StrMicroS, StrMilliS, StrSec} {
+			if strings.HasSuffix(value, strUnit) {
+				trimmed := strings.TrimSuffix(value, strUnit)
+				if _, err := strconv.ParseFloat(trimmed, 64); err == nil {
+					unit = StrTimeUnit2TimeUnit[strUnit]
+					value = trimmed
+					break
+				}
+			}
+		}
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		slo.Threshold = val * float64(unit) / MilliS
+	} else if _, hit := sloS3WorkloadMetrics[slo.Metric]; hit {
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		slo.Threshold = val
+	} else {
+		return nil, errors.New("unknown SLO metric: " + slo.Metric)
+	}
+
+	return &slo, nil
+}
+
+// Percentile returns the percentile the SLO is set on, if any.
+func (slo *SLO) Percentile() (float64, bool) {
+	if !strings.HasPrefix(slo.Stat, "p") {
+		return 0, false
+	}
+	perc, err := strconv.ParseFloat(strings.TrimPrefix(slo.Stat, "p"), 64)
+	return perc, err == nil
+}
+
+func sloStatValue(summary *DistSummary, stat string, syntaxOnly bool) (float64, error) {
+	switch stat {
+	case "mean":
+		return summary.Mean, nil
+	case "median":
+		return summary.Median, nil
+	case "min":
+		return summary.Min, nil
+	case "max":
+		return summary.Max, nil
+	case "std_dev":
+		return summary.StdDev, nil
+	case "mad":
+		return summary.MAD, nil
+	}
+	if strings.HasPrefix(stat, "p") {
+		perc, err := strconv.ParseFloat(strings.TrimPrefix(stat, "p"), 64)
+		if err != nil || perc <= 0 || perc > 100 {
+			return 0, errors.New("malformed percentile stat: " + stat)
+		}
+		if syntaxOnly {
+			return 0, nil
+		}
+		if val, hit := summary.Percentiles[PercentileKey(perc)]; hit {
+			return val, nil
+		}
+		return 0, errors.New("percentile not computed: " + stat)
+	}
+	return 0, errors.New("unknown stat: " + stat)
+}
+
+func (slo *SLO) check(val float64) bool {
+	switch slo.Op {
+	case "<":
+		return val < slo.Threshold
+	case "<=":
+		return val <= slo.Threshold
+	case ">":
+		return val > slo.Threshold
+	case ">=":
+		return val >= slo.Threshold
+	case "==":
+		return val == slo.Threshold
+	}
+	return false
+}
+
+// EvaluateSLOs evaluates the SLOs against every series of the stats,
+// computed in ms; an SLO without data fails.
+func EvaluateSLOs(slos []SLO, stats *Stats, mark string) *SLOVerdict {
+	verdict := SLOVerdict{Status: SLOStatusPass, Mark: mark, EvaluatedAt: time.Now().Unix()}
+
+	toMs := float64(StrTimeUnit2TimeUnit[stats.TimeUnit]) / MilliS
+
+	for _, slo := range slos {
+		evaluated := false
+		addResult := func(seriesMark string, val float64, err error) {
+			evaluated = true
+			res := SLOResult{SLO: slo.Expr, Mark: seriesMark, Value: val}
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				res.Pass = slo.check(val)
+			}
+			if !res.Pass {
+				verdict.Status = SLOStatusFail
+			}
+			verdict.Results = append(verdict.Results, res)
+		}
+
+		if getter, hit := sloRestartMetrics[slo.Metric]; hit {
+			for i := range stats.SeriesRestart {
+				val, err := sloStatValue(getter(&stats.SeriesRestart[i]), slo.Stat, false)
+				addResult(stats.SeriesRestart[i].Mark, val*toMs, err)
+			}
+		} else if slo.Metric == "RTT" {
+			for i := range stats.SeriesS3Workload {
+				val, err := sloStatValue(&stats.SeriesS3Workload[i].RTT, slo.Stat, false)
+				addResult(stats.SeriesS3Workload[i].Mark, val*toMs, err)
+			}
+		} else if getter, hit := sloS3WorkloadMetrics[slo.Metric]; hit {
+			for i := range stats.SeriesS3Workload {
+				val, err := getter(&stats.SeriesS3Workload[i])
+				addResult(stats.SeriesS3Workload[i].Mark, val, err)
+			}
+		}
+
+		if !evaluated {
+			addResult(mark, 0, errors.New("no data for metric: "+slo.Metric))
+		}
+	}
+
+	return &verdict
+}
@@ -0,0 +1,388 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	StoreRecordDeath       = "death"
+	StoreRecordStart       = "start"
+	StoreRecordRestart     = "restart"
+	StoreRecordS3Workload  = "s3wl"
+	StoreRecordIntegrity   = "integrity"
+	StoreRecordCampaign    = "campaign"
+	StoreRecordCampaignEnd = "campaign_end"
+)
+
+var storeEventsBucket = []byte("events")
+
+// StoreRecord is an event appended to the run store.
+type StoreRecord struct {
+	Kind    string        `json:"kind"`
+	Mark    string        `json:"mark"`
+	Death   *DeathEvent   `json:"death,omitempty"`
+	Start   *StartEvent   `json:"start,omitempty"`
+	Restart *RestartEvent `json:"restart,omitempty"`
+
+	//S3 workload
+	Op      string           `json:"op,omitempty"`
+	S3WL    *S3WorkloadEvent `json:"s3wl,omitempty"`
+	ErrDesc string           `json:"err_desc,omitempty"`
+	KeepRaw bool             `json:"keep_raw,omitempty"`
+
+	Integrity *IntegrityCheck `json:"integrity,omitempty"`
+
+	Campaign *StoreCampaign `json:"campaign,omitempty"`
+}
+
+// StoreCampaign is a snapshot of the campaign in progress, appended when it is
+// triggered and at every sweep step; the restarts recorded after it are
+// deducted from its pending ones when reloading.
+type StoreCampaign struct {
+	Mark            string           `json:"mark"`
+	RunId           string           `json:"run_id"`
+	PendingRestarts uint             `json:"pending_restarts"`
+	GracePeriod     uint             `json:"grace"`
+	LieDownPeriod   uint             `json:"lie_down"`
+	SettlePeriod    uint             `json:"settle"`
+	DeathType       string           `json:"death_type"`
+	Hooks           []Hook           `json:"hooks"`
+	SelectedNode    string           `json:"node"`
+	Sweep           *SweepConfig     `json:"sweep,omitempty"`
+	Percentiles     []float64        `json:"percentiles"`
+	CI              BootstrapConfig  `json:"CI"`
+	SLOs            []SLO            `json:"SLOs"`
+	Outliers        OutlierConfig    `json:"outliers"`
+	Plot            PlotConfig       `json:"plot"`
+	S3Workload      S3WorkloadConfig `json:"s3_workload"`
+}
+
+const (
+	storeQueueLen    = 4096
+	storeBatchLen    = 512
+	storeBatchPeriod = 200 * time.Millisecond
+)
+
+// storeOp is a record to append or, with done, a request to write the queued
+// records (to drop them with reset) that is answered on done.
+type storeOp struct {
+	val   []byte
+	reset bool
+	done  chan error
+}
+
+// RunStore appends every collected event to a bbolt file so that the
+// collected data survives a restart of the probe; a nil store is disabled.
+// The records are written in batches, one transaction each, by a single
+// writer, so a crash loses at most the last storeBatchPeriod of records.
+type RunStore struct {
+	db      *bolt.DB
+	ops     chan storeOp
+	stopped chan struct{}
+}
+
+func OpenRunStore(path string) (*RunStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(storeEventsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &RunStore{db: db, ops: make(chan storeOp, storeQueueLen), stopped: make(chan struct{})}
+	go s.writeLoop()
+	return s, nil
+}
+
+// Close writes the queued records and closes the store.
+func (s *RunStore) Close() error {
+	if s == nil {
+		return nil
+	}
+	close(s.ops)
+	<-s.stopped
+	return s.db.Close()
+}
+
+func (s *RunStore) writeLoop() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(storeBatchPeriod)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	for {
+		select {
+		case op, ok := <-s.ops:
+			if !ok {
+				s.write(batch)
+				return
+			}
+			if op.done == nil {
+				if batch = append(batch, op.val); len(batch) >= storeBatchLen {
+					s.write(batch)
+					batch = nil
+				}
+				continue
+			}
+			if op.reset {
+				op.done <- s.reset()
+			} else {
+				op.done <- s.write(batch)
+			}
+			batch = nil
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.write(batch)
+				batch = nil
+			}
+		}
+	}
+}
+
+func (s *RunStore) write(batch [][]byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(storeEventsBucket)
+		for _, val := range batch {
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			key := make([]byte, 8)
+			binary.BigEndian.PutUint64(key, seq)
+			if err := bucket.Put(key, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		Logger.Errorf("RunStore.write:%s", err.Error())
+	}
+	return err
+}
+
+func (s *RunStore) reset() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(storeEventsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(storeEventsBucket)
+		return err
+	})
+}
+
+// Append queues a record to be written to the store; the record is
+// serialized at once, so it can be changed by the caller afterwards.
+func (s *RunStore) Append(rec *StoreRecord) {
+	if s == nil {
+		return
+	}
+	if rec.S3WL != nil && rec.S3WL.Error != nil {
+		rec.ErrDesc = rec.S3WL.Error.Error()
+	}
+	val, err := json.Marshal(rec)
+	if err != nil {
+		Logger.Errorf("json.Marshal:%s", err.Error())
+		return
+	}
+	s.ops <- storeOp{val: val}
+}
+
+// Flush writes the queued records.
+func (s *RunStore) Flush() error {
+	if s == nil {
+		return nil
+	}
+	done := make(chan error)
+	s.ops <- storeOp{done: done}
+	return <-done
+}
+
+// Reset drops every record from the store, the queued ones included.
+func (s *RunStore) Reset() {
+	if s == nil {
+		return
+	}
+	done := make(chan error)
+	s.ops <- storeOp{reset: true, done: done}
+	if err := <-done; err != nil {
+		Logger.Errorf("RunStore.Reset:%s", err.Error())
+	}
+}
+
+// ForEach calls fn on every record in append order.
+func (s *RunStore) ForEach(fn func(rec *StoreRecord)) error {
+	if s == nil {
+		return errors.New("run store disabled")
+	}
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(storeEventsBucket).ForEach(func(_, val []byte) error {
+			var rec StoreRecord
+			if err := json.Unmarshal(val, &rec); err != nil {
+				Logger.Errorf("json.Unmarshal:%s", err.Error())
+				return nil
+			}
+			if rec.S3WL != nil && rec.ErrDesc != "" {
+				rec.S3WL.Error = errors.New(rec.ErrDesc)
+			}
+			fn(&rec)
+			return nil
+		})
+	})
+}
+
+// RecordCampaign appends to the store a snapshot of the campaign in progress.
+func (p *Probe) RecordCampaign() {
+	p.Store.Append(&StoreRecord{Kind: StoreRecordCampaign,
+		Mark: p.CurrentMark,
+		Campaign: &StoreCampaign{Mark: p.CurrentMark,
+			RunId:           p.CurrentRunId,
+			PendingRestarts: p.CurrentPendingRestarts,
+			GracePeriod:     p.CurrentGracePeriod,
+			LieDownPeriod:   p.CurrentLieDownPeriod,
+			SettlePeriod:    p.CurrentSettlePeriod,
+			DeathType:       p.CurrentDeathType,
+			Hooks:           p.CurrentHooks,
+			SelectedNode:    p.CurrentSelectedNode,
+			Sweep:           p.CurrentSweep,
+			Percentiles:     p.CurrentPercentiles,
+			CI:              p.CurrentCI,
+			SLOs:            p.CurrentSLOs,
+			Outliers:        p.CurrentOutliers,
+			Plot:            p.CurrentPlot,
+			S3Workload:      p.CurrentS3WorkloadCfg}})
+}
+
+func (p *Probe) restoreCampaign(campaign *StoreCampaign) {
+	p.CurrentMark = campaign.Mark
+	p.CurrentRunId = campaign.RunId
+	p.CurrentPendingRestarts = campaign.PendingRestarts
+	p.CurrentGracePeriod = campaign.GracePeriod
+	p.CurrentLieDownPeriod = campaign.LieDownPeriod
+	p.CurrentSettlePeriod = campaign.SettlePeriod
+	p.CurrentDeathType = campaign.DeathType
+	p.CurrentHooks = campaign.Hooks
+	p.CurrentSelectedNode = campaign.SelectedNode
+	p.CurrentSweep = campaign.Sweep
+	p.CurrentPercentiles = campaign.Percentiles
+	p.CurrentCI = campaign.CI
+	p.CurrentSLOs = campaign.SLOs
+	p.CurrentOutliers = campaign.Outliers
+	p.CurrentPlot = campaign.Plot
+	p.CurrentS3WorkloadCfg = campaign.S3Workload
+}
+
+// LoadFromStore reloads the collected data and the campaign in progress from
+// the store; a death not followed by its restart is restored as the current
+// one and the ids carry on from the highest ones loaded.
+func (p *Probe) LoadFromStore() error {
+	var restarts, s3wlEvents int
+	var maxId, maxS3WorkloadId int
+	err := p.Store.ForEach(func(rec *StoreRecord) {
+		switch rec.Kind {
+		case StoreRecordCampaign:
+			p.restoreCampaign(rec.Campaign)
+		case StoreRecordCampaignEnd:
+			p.ResetCurrentState()
+		case StoreRecordDeath:
+			p.CurrentMark = rec.Mark
+			p.CurrentDeath = rec.Death
+			p.CurrentStartList = nil
+		case StoreRecordStart:
+			p.CurrentStartList = append(p.CurrentStartList, rec.Start)
+		case StoreRecordRestart:
+			p.CollectedRestartRelatedData[rec.Mark] = append(p.CollectedRestartRelatedData[rec.Mark], *rec.Restart)
+			p.CurrentDeath = nil
+			p.CurrentStartList = nil
+			if p.CurrentPendingRestarts > 0 {
+				p.CurrentPendingRestarts--
+			}
+			if p.CurrentSweep != nil {
+				p.CurrentSweep.Restarts++
+			}
+			if rec.Restart.Id > maxId {
+				maxId = rec.Restart.Id
+			}
+			restarts++
+		case StoreRecordS3Workload:
+			p.collectS3WorkloadEvent(rec.Mark, rec.Op, rec.S3WL, rec.KeepRaw)
+			if rec.S3WL.Id > maxS3WorkloadId {
+				maxS3WorkloadId = rec.S3WL.Id
+			}
+			s3wlEvents++
+		case StoreRecordIntegrity:
+			p.CollectedS3WorkloadIntegrity.Record(rec.Mark, rec.Integrity)
+		}
+	})
+	p.CurrentId = maxId
+	p.CurrentS3WorkloadId = maxS3WorkloadId
+	if err == nil {
+		Logger.Infof("STORE - reloaded %d restarts, %d workload events", restarts, s3wlEvents)
+	}
+	return err
+}
+
+// ResumeCampaign carries on the campaign reloaded from the store: the
+// workload is started again and, without a restart in flight, the next
+// death is requested.
+func (p *Probe) ResumeCampaign() {
+	if p.CurrentMark == "" || (p.CurrentPendingRestarts == 0 && p.CurrentDeath == nil) {
+		if p.CurrentMark != "" {
+			Logger.Warnf("STORE - campaign %s ended before its wrap-up, dropped", p.CurrentMark)
+			id, s3WorkloadId := p.CurrentId, p.CurrentS3WorkloadId
+			p.ResetCurrentState()
+			p.CurrentId, p.CurrentS3WorkloadId = id, s3WorkloadId
+		}
+		return
+	}
+
+	Logger.Infof("STORE - resuming campaign %s, pending restarts: %d", p.CurrentMark, p.CurrentPendingRestarts)
+	p.CurrentS3WorkloadCfg.InitClient()
+	if sweep := p.CurrentSweep; sweep != nil && sweep.FillCfg != nil {
+		sweep.FillCfg.Client = S3Client_S3GW
+		if sweep.FillCfg.PayloadSpec != nil {
+			sweep.FillCfg.PayloadSpec.Reseed()
+		}
+	}
+
+	if p.CurrentDeath != nil {
+		//the radosgw start events complete the restart in flight
+		SetProbeState(ProbeStateRestarting)
+		var err error
+		if p.CurrentS3WorkloadStarted, err = p.TriggerS3ClientWorkload(); err != nil {
+			Logger.Errorf("TriggerS3ClientWorkload:%s", err.Error())
+		}
+		return
+	}
+
+	SetProbeState(ProbeStateRunning)
+	time.AfterFunc(time.Duration(Cfg.WaitMSecsBeforeTriggerDeath)*time.Millisecond, p.RequestDie)
+}
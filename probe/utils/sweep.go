@@ -0,0 +1,82 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"strconv"
+)
+
+// SweepConfig drives a sweep: each step grows the dataset with a fill
+// of FillCfg.ObjCount objects and then performs RestartsPerStep restarts
+// under a mark tagged with the dataset size.
+type SweepConfig struct {
+	Steps           uint
+	RestartsPerStep uint
+	BaseMark        string
+	FillCfg         *FillConfig
+
+	CurrentStep   uint
+	Restarts      uint   //restarts collected since the sweep was triggered
+	Filled        uint64 //objects actually put by the steps
+	StepMarks     []string
+	StepObjCounts []uint64
+}
+
+func (sweep *SweepConfig) datasetSize(step uint) uint64 {
+	return sweep.FillCfg.StartIdx + uint64(step+1)*sweep.FillCfg.ObjCount
+}
+
+// SweepStep fills the bucket for the next step of the sweep and switches
+// the current mark to the step's one, tagged with the objects actually in
+// the dataset: a step whose fill failed altogether keeps the mark of the
+// previous one.
+func (p *Probe) SweepStep() {
+	sweep := p.CurrentSweep
+	if sweep == nil || sweep.CurrentStep >= sweep.Steps {
+		return
+	}
+
+	fillCfg := *sweep.FillCfg
+	fillCfg.StartIdx = sweep.datasetSize(sweep.CurrentStep) - fillCfg.ObjCount
+	Logger.Infof("SWEEP - step %d/%d, filling objects [%d, %d) ...",
+		sweep.CurrentStep+1, sweep.Steps, fillCfg.StartIdx, fillCfg.StartIdx+fillCfg.ObjCount)
+	summaries, err := RunFill(&fillCfg, true, false)
+	if err != nil {
+		Logger.Errorf("SweepStep: RunFill:%s", err.Error())
+	}
+	for _, summary := range summaries {
+		sweep.Filled += summary.Done - summary.Failures
+		if summary.Failures > 0 {
+			Logger.Warnf("SWEEP - step %d/%d, %d objects not filled", sweep.CurrentStep+1, sweep.Steps, summary.Failures)
+		}
+	}
+
+	objCount := sweep.FillCfg.StartIdx + sweep.Filled
+	p.CurrentMark = sweep.BaseMark + "_" + strconv.FormatUint(objCount, 10) + "obj"
+	if n := len(sweep.StepMarks); n == 0 || sweep.StepMarks[n-1] != p.CurrentMark {
+		sweep.StepMarks = append(sweep.StepMarks, p.CurrentMark)
+		sweep.StepObjCounts = append(sweep.StepObjCounts, objCount)
+	}
+	sweep.CurrentStep++
+	p.RecordCampaign()
+}
+
+// SweepInterpose is run as a pre-death hook during a sweep: it moves to the
+// next step once the restarts of the current one have been performed.
+func (p *Probe) SweepInterpose() {
+	if p.CurrentSweep == nil || p.CurrentSweep.RestartsPerStep == 0 {
+		return
+	}
+	if restarts := p.CurrentSweep.Restarts; restarts > 0 && restarts%p.CurrentSweep.RestartsPerStep == 0 {
+		p.SweepStep()
+	}
+}
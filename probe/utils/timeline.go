@@ -0,0 +1,180 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"image/color"
+	"math"
+	"sort"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// RestartIntervals implements the Plotter interface, shading on a wall-clock
+// x axis the death->main and main->frontend-up intervals of the restarts
+// and marking every death with the glyph of its death type.
+type RestartIntervals struct {
+	Restarts []RestartEvent
+
+	// ColorToMain, ColorToFrontendUp are the colors of the shaded intervals
+	ColorToMain       color.Color
+	ColorToFrontendUp color.Color
+
+	// DeathTypeGlyphs maps a death type to the glyph of its marker
+	DeathTypeGlyphs map[string]draw.GlyphStyle
+
+	// DeathTypes is the sorted list of the death types found in the data
+	DeathTypes []string
+}
+
+func restartDeathType(evt *RestartEvent) string {
+	if evt.How != "" {
+		return evt.How
+	}
+	return evt.Death.Type
+}
+
+// NewRestartIntervals creates a new restart intervals plotter for the given restarts.
+func NewRestartIntervals(restarts []RestartEvent) *RestartIntervals {
+	intervals := &RestartIntervals{
+		Restarts:          restarts,
+		ColorToMain:       color.NRGBA{R: 150, G: 170, B: 230, A: 110},
+		ColorToFrontendUp: color.NRGBA{R: 255, G: 215, B: 120, A: 110},
+		DeathTypeGlyphs:   make(map[string]draw.GlyphStyle),
+	}
+
+	for i := range restarts {
+		intervals.DeathTypeGlyphs[restartDeathType(&restarts[i])] = draw.GlyphStyle{}
+	}
+	for deathType := range intervals.DeathTypeGlyphs {
+		intervals.DeathTypes = append(intervals.DeathTypes, deathType)
+	}
+	sort.Strings(intervals.DeathTypes)
+
+	for i, deathType := range intervals.DeathTypes {
+		intervals.DeathTypeGlyphs[deathType] = draw.GlyphStyle{
+			Color:  color.Black,
+			Radius: vg.Points(4),
+			Shape:  plotutil.Shape(i),
+		}
+	}
+
+	return intervals
+}
+
+// AddLegend adds an entry for each interval and one for each death type to the plot's legend.
+func (intervals *RestartIntervals) AddLegend(plt *plot.Plot) {
+	toMain, _ := plotter.NewPolygon()
+	toMain.Color = intervals.ColorToMain
+	toMain.LineStyle.Width = 0
+	plt.Legend.Add("death-main", toMain)
+
+	toFUp, _ := plotter.NewPolygon()
+	toFUp.Color = intervals.ColorToFrontendUp
+	toFUp.LineStyle.Width = 0
+	plt.Legend.Add("main-frontend-up", toFUp)
+
+	for _, deathType := range intervals.DeathTypes {
+		plt.Legend.Add(deathType, &plotter.Scatter{GlyphStyle: intervals.DeathTypeGlyphs[deathType]})
+	}
+}
+
+func (intervals *RestartIntervals) shade(c draw.Canvas, x0 vg.Length, x1 vg.Length, col color.Color) {
+	if x1 <= x0 {
+		return
+	}
+	rect := []vg.Point{{X: x0, Y: c.Min.Y}, {X: x1, Y: c.Min.Y}, {X: x1, Y: c.Max.Y}, {X: x0, Y: c.Max.Y}}
+	c.FillPolygon(col, c.ClipPolygonX(rect))
+}
+
+// Plot implements the Plot method of the plot.Plotter interface.
+func (intervals *RestartIntervals) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, _ := plt.Transforms(&c)
+
+	for i := range intervals.Restarts {
+		it := &intervals.Restarts[i]
+		death := trX(float64(it.Death.Ts) / Sec)
+		mainX := trX(float64(it.StartMain.Ts) / Sec)
+		fUp := trX(float64(it.StartFrontendUp.Ts) / Sec)
+
+		intervals.shade(c, death, mainX, intervals.ColorToMain)
+		intervals.shade(c, mainX, fUp, intervals.ColorToFrontendUp)
+
+		glyph := intervals.DeathTypeGlyphs[restartDeathType(it)]
+		pt := vg.Point{X: death, Y: c.Max.Y - glyph.Radius}
+		if c.ContainsX(pt.X) {
+			c.DrawGlyph(glyph, pt)
+		}
+	}
+}
+
+// DataRange implements the DataRange method
+// of the plot.DataRanger interface.
+func (intervals *RestartIntervals) DataRange() (xmin, xmax, ymin, ymax float64) {
+	xmin = math.Inf(1)
+	xmax = math.Inf(-1)
+	for _, it := range intervals.Restarts {
+		xmin = math.Min(xmin, float64(it.Death.Ts)/Sec)
+		xmax = math.Max(xmax, float64(it.StartFrontendUp.Ts)/Sec)
+	}
+	return
+}
+
+// GenerateTimelinePlot plots on a wall-clock x axis the S3 workload RTT bars
+// over the shaded restart intervals.
+func (p *Probe) GenerateTimelinePlot(mark string, timeUnit string, genTS string, plotCfg PlotConfig) ([]string, error) {
+	restartEvents, hitRestarts := p.CollectedRestartRelatedData[mark]
+	evtSeries, _, hitS3WL := p.s3WorkloadEntries(mark, restartEvents, StrTimeUnit2TimeUnit[timeUnit])
+	if !hitRestarts && !hitS3WL {
+		Logger.Errorf("GenerateTimelinePlot: no series with mark: %s", mark)
+		return nil, errors.New("no series with mark")
+	}
+
+	plt := plot.New()
+	plt.Add(plotter.NewGrid())
+
+	plt.Title.Text = "Timeline: " + mark
+	plt.X.Label.Text = "Time (UTC)"
+	plt.X.Tick.Marker = plot.TimeTicks{Format: "15:04:05"}
+	plt.Y.Label.Text = "RTT: " + timeUnit
+	plt.Legend.Top = true
+
+	if hitRestarts && len(restartEvents) > 0 {
+		intervals := NewRestartIntervals(restartEvents)
+		plt.Add(intervals)
+		intervals.AddLegend(plt)
+	}
+
+	if hitS3WL && len(evtSeries) > 0 {
+		bars, err := NewVBars(&evtSeries, timeUnit)
+		if err != nil {
+			Logger.Errorf("NewVBars: %s", err.Error())
+			return nil, err
+		}
+		bars.WallClock = true
+		plt.Add(bars)
+		bars.AddLegend(plt)
+	}
+
+	fBase := genTS + "_" + mark + "_timeline"
+
+	fNames, err := savePlot(plt, fBase, plotCfg)
+	if err != nil {
+		Logger.Errorf("GenerateTimelinePlot: Saving plot: %s", fBase)
+	}
+	return fNames, err
+}
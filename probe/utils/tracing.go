@@ -0,0 +1,172 @@
+// Copyright © 2023 SUSE LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "s3gw-probe"
+
+// InitTracing exports the traces to the OTLP/HTTP endpoint (host:port);
+// without an endpoint the spans are not recorded.
+// The returned function flushes and stops the exporter.
+func InitTracing(endpoint string, insecure bool) (func(), error) {
+	if endpoint == "" {
+		return func() {}, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(tracerName))))
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		if err := provider.Shutdown(context.Background()); err != nil {
+			Logger.Errorf("TracerProvider.Shutdown:%s", err.Error())
+		}
+	}, nil
+}
+
+// CycleTrace is the trace of a restart cycle: the root span goes from the
+// preparation of the death to the frontend-up.
+type CycleTrace struct {
+	ctx  context.Context
+	root trace.Span
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// startCycleTrace starts the trace of the next restart cycle, if not yet started.
+func (p *Probe) startCycleTrace() {
+	p.TraceMtx.Lock()
+	defer p.TraceMtx.Unlock()
+
+	if p.CurrentCycleTrace != nil {
+		return
+	}
+	ctx, root := tracer().Start(context.Background(), "restart-cycle",
+		trace.WithAttributes(attribute.String("mark", p.CurrentMark),
+			attribute.Int("restart_id", p.nextRestartId(p.CurrentMark)),
+			attribute.String("death_type", p.CurrentDeathType),
+			attribute.String("node", p.currentNode())))
+	p.CurrentCycleTrace = &CycleTrace{ctx: ctx, root: root}
+}
+
+// traceSpan starts a child span of the current cycle and returns the function ending it.
+func (p *Probe) traceSpan(name string, attrs ...attribute.KeyValue) func(err error) {
+	p.TraceMtx.Lock()
+	defer p.TraceMtx.Unlock()
+
+	if p.CurrentCycleTrace == nil {
+		return func(error) {}
+	}
+	_, span := tracer().Start(p.CurrentCycleTrace.ctx, name, trace.WithAttributes(attrs...))
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// endCycleTrace adds the main and frontend-up spans, timed by the radosgw's
+// events, and ends the cycle at the frontend-up.
+func (p *Probe) endCycleTrace(evt *RestartEvent, missingMain bool, missingFrontendUp bool) {
+	p.TraceMtx.Lock()
+	defer p.TraceMtx.Unlock()
+
+	if p.CurrentCycleTrace == nil {
+		//unsolicited restart: the cycle begins at the death
+		ctx, root := tracer().Start(context.Background(), "restart-cycle",
+			trace.WithTimestamp(time.Unix(0, evt.Death.Ts)),
+			trace.WithAttributes(attribute.String("mark", p.CurrentMark)))
+		p.CurrentCycleTrace = &CycleTrace{ctx: ctx, root: root}
+	}
+	cycle := p.CurrentCycleTrace
+	p.CurrentCycleTrace = nil
+
+	deathTs := time.Unix(0, evt.Death.Ts)
+	mainTs := time.Unix(0, evt.StartMain.Ts)
+	fUpTs := time.Unix(0, evt.StartFrontendUp.Ts)
+
+	_, mainSpan := tracer().Start(cycle.ctx, "main", trace.WithTimestamp(deathTs))
+	if missingMain {
+		mainSpan.SetStatus(codes.Error, "missing main event")
+	}
+	mainSpan.End(trace.WithTimestamp(mainTs))
+
+	_, fUpSpan := tracer().Start(cycle.ctx, "frontend-up", trace.WithTimestamp(mainTs))
+	if missingFrontendUp {
+		fUpSpan.SetStatus(codes.Error, "missing frontend-up event")
+	}
+	fUpSpan.End(trace.WithTimestamp(fUpTs))
+
+	cycle.root.SetAttributes(attribute.Int("restart_id", evt.Id),
+		attribute.String("death_type", evt.How),
+		attribute.String("node", evt.Node),
+		attribute.Float64("to_main_ms", float64(evt.StartMain.Ts-evt.Death.Ts)/MilliS),
+		attribute.Float64("to_frontend_up_ms", float64(evt.StartFrontendUp.Ts-evt.Death.Ts)/MilliS))
+	if missingMain || missingFrontendUp {
+		cycle.root.SetStatus(codes.Error, "incomplete restart")
+	}
+	cycle.root.End(trace.WithTimestamp(fUpTs))
+}
+
+// traceS3WorkloadEvent emits a S3 workload request as a span linked
+// to the restart cycle in progress.
+func (p *Probe) traceS3WorkloadEvent(op string, evt *S3WorkloadEvent) {
+	p.TraceMtx.Lock()
+	var links []trace.Link
+	if p.CurrentCycleTrace != nil {
+		links = append(links, trace.Link{SpanContext: p.CurrentCycleTrace.root.SpanContext()})
+	}
+	p.TraceMtx.Unlock()
+
+	_, span := tracer().Start(context.Background(), "s3."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(time.Unix(0, evt.StartTs)),
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.String("mark", p.CurrentMark),
+			attribute.Int("wl_id", evt.Id),
+			attribute.Int64("size", evt.Size),
+			attribute.Int("retries", evt.Retries),
+			attribute.Int("http_status", evt.HTTPStatus)))
+	if evt.Error != nil {
+		span.RecordError(evt.Error)
+		span.SetStatus(codes.Error, evt.ErrCat)
+	}
+	span.End(trace.WithTimestamp(time.Unix(0, evt.EndTs)))
+}
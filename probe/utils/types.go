@@ -21,17 +21,28 @@ type Config struct {
 	S3GWS3ForcePathStyle        bool
 	WaitMSecsBeforeTriggerDeath uint //msec
 	WaitMSecsBeforeSetReplicas1 uint //msec
+	SettleMSecsAfterFrontendUp  uint //msec
 	CollectRestartAtEvent       string
 	SaveDataS3Endpoint          string
 	SaveDataS3ForcePathStyle    bool
 	SaveDataBucket              string
+	StorePath                   string
+	OTLPEndpoint                string
+	OTLPInsecure                bool
 }
 
 type S3WorkloadEvent struct {
-	Id      int
-	StartTs int64 //start timestamp of this event
-	EndTs   int64 //end timestamp of this event
-	Error   error //error for this event
+	Id         int
+	RunId      string //campaign this event was collected in
+	Op         string //S3 operation of this event
+	StartTs    int64  //start timestamp of this event
+	EndTs      int64  //end timestamp of this event
+	Size       int64  //size of the object sent, bytes
+	Error      error  `json:"-"` //error for this event, persisted by the run store as err_desc
+	Retries    int    //retries performed by the SDK for this event
+	ErrCode    string //final S3/SDK error code for this event
+	HTTPStatus int    //final HTTP status code for this event, 0 if no response
+	ErrCat     string //error category for this event
 }
 
 type DeathEvent struct {
@@ -46,61 +57,101 @@ type StartEvent struct {
 
 type RestartEvent struct {
 	Id              int
+	RunId           string //campaign this restart was collected in
 	Death           *DeathEvent
 	StartMain       *StartEvent
 	StartFrontendUp *StartEvent
+	SettlePeriod    uint            //msec, window after frontend-up still attributed to this restart
+	Node            string          //node where the radosgw was scheduled, if selected by the probe
+	How             string          //death type requested by the campaign
+	K8sEvents       []K8sEventEntry `json:",omitempty"` //captured when the restart is collected
 }
 
 type RestartEntry struct {
-	Id                          int   `json:"restart_id"`
-	RestartDurationToMain       int64 `json:"duration_to_main"`
-	RestartDurationToFrontendUp int64 `json:"duration_to_frontend_up"`
-	FUpMainDelta                int64 `json:"frontend_up_main_delta"`
+	Id                          int     `json:"restart_id"`
+	RunId                       string  `json:"run_id"`
+	DeathTs                     int64   `json:"death_ts"`
+	Node                        string  `json:"node"`
+	DeathType                   string  `json:"death_type"`
+	RestartDurationToMain       float64 `json:"duration_to_main"`
+	RestartDurationToFrontendUp float64 `json:"duration_to_frontend_up"`
+	FUpMainDelta                float64 `json:"frontend_up_main_delta"`
+	WLCount                     uint    `json:"wl_count"`
+	WLErrCount                  uint    `json:"wl_err_count"`
+	WLMaxRTT                    float64 `json:"wl_max_RTT"`
+	Outlier                     bool    `json:"outlier"`
+}
+
+type K8sEventEntry struct {
+	Ts        int64  `json:"ts"`
+	Namespace string `json:"namespace"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	Object    string `json:"object"`
+	Message   string `json:"message"`
+}
+
+type RestartOutlierEntry struct {
+	Id                          int             `json:"restart_id"`
+	RestartDurationToMain       float64         `json:"duration_to_main"`
+	RestartDurationToFrontendUp float64         `json:"duration_to_frontend_up"`
+	K8sEvents                   []K8sEventEntry `json:"k8s_events"` //around the restart window
 }
 
 type SeriesRestartEntry struct {
-	Mark             string         `json:"mark"`
-	MinMain          int64          `json:"min_to_main"`
-	MaxMain          int64          `json:"max_to_main"`
-	MeanMain         int64          `json:"mean_to_main"`
-	Perc99Main       int64          `json:"99p_to_main"`
-	Perc95Main       int64          `json:"95p_to_main"`
-	PercNR99Main     int64          `json:"99pNR_to_main"`
-	PercNR95Main     int64          `json:"95pNR_to_main"`
-	MinFrontUp       int64          `json:"min_to_frontend_up"`
-	MaxFrontUp       int64          `json:"max_to_frontend_up"`
-	MeanFrontUp      int64          `json:"mean_to_frontend_up"`
-	Perc99FrontUp    int64          `json:"99p_to_frontend_up"`
-	Perc95FrontUp    int64          `json:"95p_to_frontend_up"`
-	PercNR99FrontUp  int64          `json:"99pNR_to_frontend_up"`
-	PercNR95FrontUp  int64          `json:"95pNR_to_frontend_up"`
-	MinFUpMainD      int64          `json:"min_frontend_up_main_delta"`
-	MaxFUpMainD      int64          `json:"max_frontend_up_main_delta"`
-	MeanFUpMainD     int64          `json:"mean_frontend_up_main_delta"`
-	Perc99FUpMainD   int64          `json:"99p_frontend_up_main_delta"`
-	Perc95FUpMainD   int64          `json:"95p_frontend_up_main_delta"`
-	PercNR99FUpMainD int64          `json:"99pNR_frontend_up_main_delta"`
-	PercNR95FUpMainD int64          `json:"95pNR_frontend_up_main_delta"`
-	Data             []RestartEntry `json:"data"`
+	Mark         string      `json:"mark"`
+	ToMain       DistSummary `json:"to_main"`
+	ToFrontendUp DistSummary `json:"to_frontend_up"`
+	FUpMainDelta DistSummary `json:"frontend_up_main_delta"`
+
+	Outliers               []RestartOutlierEntry `json:"outliers"`
+	ToMainNoOutliers       DistSummary           `json:"to_main_no_outliers"`
+	ToFrontendUpNoOutliers DistSummary           `json:"to_frontend_up_no_outliers"`
+	FUpMainDeltaNoOutliers DistSummary           `json:"frontend_up_main_delta_no_outliers"`
+
+	Data []RestartEntry `json:"data"`
 }
 
 type S3WorkloadEntry struct {
-	Id      int     `json:"wl_id"`
-	Start   int64   `json:"start"`
-	End     int64   `json:"end"`
-	RTT     float64 `json:"rtt"`
-	ErrDesc string  `json:"err_desc"`
+	Id         int     `json:"wl_id"`
+	RunId      string  `json:"run_id"`
+	Op         string  `json:"op"`
+	Start      int64   `json:"start"`
+	End        int64   `json:"end"`
+	Size       int64   `json:"size"`
+	RTT        float64 `json:"rtt"`
+	RestartId  int     `json:"restart_id"` //0 when outside any restart window
+	Retries    int     `json:"retries"`
+	ErrCode    string  `json:"err_code"`
+	HTTPStatus int     `json:"http_status"`
+	ErrCat     string  `json:"err_category"`
+	ErrDesc    string  `json:"err_desc"`
 }
+type DowntimeEntry struct {
+	RestartId      int     `json:"restart_id"`
+	FirstFailure   int64   `json:"first_failure"`
+	LastFailure    int64   `json:"last_failure"`
+	Unavailability float64 `json:"unavailability"`
+	FailedCount    uint    `json:"failed_count"`
+}
+
 type SeriesS3WorkloadEntry struct {
-	Mark        string            `json:"mark"`
-	MinRTT      int64             `json:"min_RTT"`
-	MaxRTT      int64             `json:"max_RTT"`
-	MeanRTT     int64             `json:"mean_RTT"`
-	Perc99RTT   int64             `json:"99p_RTT"`
-	Perc95RTT   int64             `json:"95p_RTT"`
-	PercNR99RTT int64             `json:"99pNR_RTT"`
-	PercNR95RTT int64             `json:"95pNR_RTT"`
-	Data        []S3WorkloadEntry `json:"data"`
+	Mark              string                 `json:"mark"`
+	RTT               DistSummary            `json:"RTT"`
+	RTTByOp           map[string]DistSummary `json:"RTT_by_op"` //from the latency histograms
+	ErrCount          uint                   `json:"err_count"`
+	ErrCatCount       map[string]uint        `json:"err_category_count"`
+	TotalCount        uint                   `json:"count"`
+	FirstFailure      int64                  `json:"first_failure"`
+	LastFailure       int64                  `json:"last_failure"`
+	Unavailability    float64                `json:"unavailability"`
+	Availability      float64                `json:"availability"`      //% of successful requests
+	TimeAvailability  float64                `json:"time_availability"` //% of run time without unavailability
+	Downtimes         []DowntimeEntry        `json:"downtimes"`
+	RawEvents         bool                   `json:"raw_events"`       //false: the time based metrics are not measured
+	IntegrityChecks   uint                   `json:"integrity_checks"` //read-backs of the acknowledged objects (verify=1)
+	IntegrityFailures uint                   `json:"integrity_failures"`
+	Data              []S3WorkloadEntry      `json:"data"`
 }
 
 type Stats struct {
@@ -109,4 +160,9 @@ type Stats struct {
 	SeriesS3WorkloadCount uint                    `json:"series_s3_workload_count"`
 	SeriesS3Workload      []SeriesS3WorkloadEntry `json:"series_s3_workload"`
 	TimeUnit              string                  `json:"time_unit"`
+	Percentiles           []float64               `json:"percentiles"`
+	CI                    BootstrapConfig         `json:"CI"`
+	Outliers              OutlierConfig           `json:"outliers"`
+	Plot                  PlotConfig              `json:"plot"`
+	SLO                   *SLOVerdict             `json:"SLO,omitempty"`
 }